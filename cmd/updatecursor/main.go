@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/CoGorm/updateCursor/internal/cli"
@@ -16,6 +17,11 @@ func main() {
 		if err.Error() == "update needed" {
 			os.Exit(10) // Exit with status 10 for update needed (matching bash script behavior)
 		}
+		// Commands can request a specific exit status
+		var exitErr *cli.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		// Other errors exit with status 1
 		os.Exit(1)
 	}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/CoGorm/updateCursor/internal/config"
+	"github.com/CoGorm/updateCursor/internal/updater"
+)
+
+// heartbeat periodically prints a minimal progress line when output is not a
+// terminal, so CI consoles that kill silent jobs see activity without the
+// log being flooded with progress bar redraws
+type heartbeat struct {
+	w        io.Writer
+	interval time.Duration
+
+	mu   sync.Mutex
+	last updater.ProgressUpdate
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newHeartbeat creates a heartbeat writing to w every interval
+func newHeartbeat(w io.Writer, interval time.Duration) *heartbeat {
+	return &heartbeat{
+		w:        w,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Update records the latest progress to report on the next beat
+func (h *heartbeat) Update(update updater.ProgressUpdate) {
+	h.mu.Lock()
+	h.last = update
+	h.mu.Unlock()
+}
+
+// Start begins emitting heartbeat lines until Stop is called
+func (h *heartbeat) Start() {
+	go func() {
+		defer close(h.done)
+
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				h.beat()
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the heartbeat and waits for its goroutine to exit, so no line is
+// written after Stop returns. It writes no final line, since the download may
+// have failed.
+func (h *heartbeat) Stop() {
+	close(h.stop)
+	<-h.done
+}
+
+// beat writes a single heartbeat line for the latest progress
+func (h *heartbeat) beat() {
+	h.mu.Lock()
+	update := h.last
+	h.mu.Unlock()
+
+	if update.TotalBytes > 0 {
+		percentage := update.Percentage
+		if percentage > 100 {
+			percentage = 100
+		}
+		fmt.Fprintf(h.w, "... %.0f%% ...\n", percentage)
+		return
+	}
+
+	fmt.Fprintf(h.w, "... %.1f MB ...\n", float64(update.BytesDownloaded)/(1024*1024))
+}
+
+// startProgress installs the progress display for a download and returns a
+// function to call once the download finishes. Terminals get the progress
+// bar, other outputs get a heartbeat when an interval is configured.
+func startProgress(up *updater.Updater, cfg *config.Config) func() {
+	if isTerminal(os.Stdout) || cfg.HeartbeatInterval <= 0 {
+		chars := resolveProgressChars(cfg, os.Getenv)
+		up.SetProgressCallback(func(update updater.ProgressUpdate) {
+			displayProgress(update, chars)
+		})
+		return func() {}
+	}
+
+	hb := newHeartbeat(os.Stdout, cfg.HeartbeatInterval)
+	up.SetProgressCallback(hb.Update)
+	hb.Start()
+	return hb.Stop
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CoGorm/updateCursor/internal/updater"
+)
+
+// syncBuffer is a bytes.Buffer safe for use from the heartbeat goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHeartbeatCadenceDuringSlowDownload(t *testing.T) {
+	// Serve 20 chunks 20ms apart, roughly 400ms in total
+	const chunks = 20
+	const chunkSize = 1000
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/stable/linux-x64":
+			http.Redirect(w, r, "/download/Cursor-1.0.0-x86_64.AppImage", http.StatusFound)
+		case "/download/Cursor-1.0.0-x86_64.AppImage":
+			w.Header().Set("Content-Length", "20000")
+			if r.Method == http.MethodHead {
+				return
+			}
+			data := make([]byte, chunkSize)
+			for i := 0; i < chunks; i++ {
+				w.Write(data)
+				w.(http.Flusher).Flush()
+				time.Sleep(20 * time.Millisecond)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	up := updater.NewUpdater(server.URL+"/download/stable/linux-x64", t.TempDir(), nil)
+
+	var out syncBuffer
+	hb := newHeartbeat(&out, 50*time.Millisecond)
+	up.SetProgressCallback(hb.Update)
+
+	start := time.Now()
+	hb.Start()
+	_, err := up.DownloadCursor()
+	hb.Stop()
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("Failed to download Cursor: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+	// One line per interval, allowing for scheduling jitter
+	expected := int(elapsed / (50 * time.Millisecond))
+	if len(lines) < expected/2 || len(lines) > expected+1 {
+		t.Errorf("Expected about %d heartbeat lines in %s, got %d: %q", expected, elapsed, len(lines), out.String())
+	}
+
+	lineFormat := regexp.MustCompile(`^\.\.\. [0-9]+% \.\.\.$`)
+	for _, line := range lines {
+		if !lineFormat.MatchString(line) {
+			t.Errorf("Expected minimal heartbeat line, got %q", line)
+		}
+	}
+}
+
+func TestHeartbeatWithoutTotalSize(t *testing.T) {
+	var out syncBuffer
+	hb := newHeartbeat(&out, time.Hour)
+
+	hb.Update(updater.ProgressUpdate{BytesDownloaded: 3 * 1024 * 1024})
+	hb.beat()
+
+	if strings.TrimSpace(out.String()) != "... 3.0 MB ..." {
+		t.Errorf("Expected downloaded size heartbeat, got %q", out.String())
+	}
+}
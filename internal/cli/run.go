@@ -1,9 +1,16 @@
 package cli
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/CoGorm/updateCursor/internal/config"
@@ -18,8 +25,20 @@ const (
 	launchLink         = "Cursor.AppImage"
 	ledgerFile         = ".cursor-versions.log"
 	versionFile        = ".cursor-version"
+	checkCacheFile     = "check-cache.json"
 )
 
+// ExitError is returned when a command should exit with a specific status
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	return e.Message
+}
+
 // Run executes the CLI application with the given arguments
 func Run(args []string) error {
 	if len(args) < 1 {
@@ -41,6 +60,10 @@ func executeCommand(command string, args []string) error {
 		return fmt.Errorf("error loading config: %v", err)
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
 	// Expand paths in config
 	err = cfg.ExpandPaths()
 	if err != nil {
@@ -51,26 +74,61 @@ func executeCommand(command string, args []string) error {
 	workDir := cfg.DownloadDir
 	ledgerPath := cfg.LedgerPath
 
+	downloadURL := cfg.DownloadURL
+	if downloadURL == "" {
+		downloadURL = defaultDownloadURL
+	}
+
 	// Create updater instance with config
-	up := updater.NewUpdater(defaultDownloadURL, workDir, cfg)
+	up := updater.NewUpdater(downloadURL, workDir, cfg)
+	up.SetCheckCache(filepath.Join(filepath.Dir(ledgerPath), checkCacheFile), cfg.CheckInterval)
+	up.SetWarningCallback(func(message string) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+	})
 
 	// Create ledger instance
-	led := ledger.NewLedger(ledgerPath)
+	led := newLedger(ledgerPath, cfg)
 
 	switch command {
 	case "check":
 		return executeCheck(up)
 	case "update":
-		return executeUpdate(up, led, cfg)
+		opts, err := parseUpdateArgs(args, cfg)
+		if err != nil {
+			return err
+		}
+		if err := configureVerifiers(up, cfg); err != nil {
+			return err
+		}
+		return executeUpdate(up, led, cfg, opts)
 	case "force":
+		if err := configureVerifiers(up, cfg); err != nil {
+			return err
+		}
 		return executeForce(up, led, cfg)
 	case "list":
-		return executeList(led)
+		opts, err := parseListArgs(args)
+		if err != nil {
+			return err
+		}
+		return executeList(os.Stdout, led, opts)
 	case "switch":
-		if len(args) < 1 {
-			return fmt.Errorf("usage: %s switch <version>", os.Args[0])
+		ver, opts, err := parseSwitchArgs(args)
+		if err != nil {
+			return err
+		}
+		opts.Confirm = confirmOnTerminal
+		return executeSwitch(up, led, ver, cfg, opts)
+	case "versions":
+		opts, err := parseVersionsArgs(args)
+		if err != nil {
+			return err
 		}
-		return executeSwitch(up, led, args[0], cfg)
+		return executeVersions(os.Stdout, up, opts)
+	case "relink":
+		return executeRelink(up)
+	case "dedupe":
+		return executeDedupe(up)
 	case "-h", "--help":
 		showUsage()
 		return nil
@@ -79,13 +137,42 @@ func executeCommand(command string, args []string) error {
 	}
 }
 
+// newLedger creates the ledger, stamping entries with the OS user when enabled
+func newLedger(ledgerPath string, cfg *config.Config) *ledger.Ledger {
+	led := ledger.NewLedger(ledgerPath)
+	if cfg.RecordUser {
+		led.SetUser(currentUsername())
+	}
+	return led
+}
+
+// configureVerifiers sets up the verifiers run before a download is
+// installed. Only commands that install need them, so a bad verifier config
+// doesn't break the others.
+func configureVerifiers(up *updater.Updater, cfg *config.Config) error {
+	verifiers, err := updater.NewVerifiers(cfg.Verifiers, cfg)
+	if err != nil {
+		return fmt.Errorf("error configuring verifiers: %v", err)
+	}
+	up.SetVerifiers(verifiers...)
+	return nil
+}
+
+// currentUsername returns the name of the OS user running the command
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
 func executeCheck(up *updater.Updater) error {
 	localVersion, err := up.GetLocalVersion()
 	if err != nil {
 		return fmt.Errorf("error getting local version: %v", err)
 	}
 
-	remoteVersion, err := up.GetRemoteVersion()
+	remoteVersion, err := up.GetRemoteVersionCached()
 	if err != nil {
 		return fmt.Errorf("error getting remote version: %v", err)
 	}
@@ -110,16 +197,55 @@ func executeCheck(up *updater.Updater) error {
 	return nil
 }
 
-func executeUpdate(up *updater.Updater, led *ledger.Ledger, cfg *config.Config) error {
-	// Check if update is needed
-	needsUpdate, remoteVersion, err := up.CheckForUpdates()
+// updateOptions controls the update command
+type updateOptions struct {
+	UpToDateExitCode int    // exit status when no update was performed
+	ExpectedVersion  string // fail unless the remote latest is exactly this version
+}
+
+// parseUpdateArgs parses the flags of the update command, using config values as defaults
+func parseUpdateArgs(args []string, cfg *config.Config) (updateOptions, error) {
+	var opts updateOptions
+
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	fs.IntVar(&opts.UpToDateExitCode, "uptodate-exit-code", cfg.UpToDateExitCode, "exit status when already up to date")
+	fs.StringVar(&opts.ExpectedVersion, "expected-version", "", "fail unless the remote latest is exactly this version")
+
+	if err := fs.Parse(args); err != nil {
+		return updateOptions{}, err
+	}
+
+	if opts.UpToDateExitCode < 0 || opts.UpToDateExitCode > 255 {
+		return updateOptions{}, fmt.Errorf("--uptodate-exit-code must be between 0 and 255: %d", opts.UpToDateExitCode)
+	}
+
+	return opts, nil
+}
+
+func executeUpdate(up *updater.Updater, led *ledger.Ledger, cfg *config.Config, opts updateOptions) error {
+	// Check if update is needed. A pin must be compared against the live
+	// remote, not a cached check.
+	check := up.CheckForUpdates
+	if opts.ExpectedVersion != "" {
+		check = up.CheckForUpdatesLive
+	}
+
+	needsUpdate, remoteVersion, err := check()
 	if err != nil {
 		return fmt.Errorf("error checking for updates: %v", err)
 	}
 
+	// Pinned provisioning must never install anything but the expected version
+	if opts.ExpectedVersion != "" && remoteVersion != opts.ExpectedVersion {
+		return fmt.Errorf("remote version %s does not match expected version %s", remoteVersion, opts.ExpectedVersion)
+	}
+
 	if !needsUpdate {
 		localVersion, _ := up.GetLocalVersion()
 		fmt.Printf("✅ Already up to date (%s).\n", localVersion)
+		if opts.UpToDateExitCode != 0 {
+			return &ExitError{Code: opts.UpToDateExitCode, Message: "already up to date"}
+		}
 		return nil
 	}
 
@@ -132,12 +258,11 @@ func executeUpdate(up *updater.Updater, led *ledger.Ledger, cfg *config.Config)
 		fmt.Printf("🔄 Test mode: Simulating download...\n")
 	}
 
-	up.SetProgressCallback(func(update updater.ProgressUpdate) {
-		displayProgress(update)
-	})
+	stopProgress := startProgress(up, cfg)
 
-	// Download the update
-	filename, err := up.DownloadCursor()
+	// Download the version that was checked, even if the remote has moved on
+	filename, err := up.Download(updater.DownloadOptions{Version: remoteVersion})
+	stopProgress()
 	if err != nil {
 		return fmt.Errorf("error downloading Cursor: %v", err)
 	}
@@ -145,17 +270,37 @@ func executeUpdate(up *updater.Updater, led *ledger.Ledger, cfg *config.Config)
 	// Add spacing after download completion
 	fmt.Println()
 
-	// Calculate SHA256
+	// Calculate hash with the configured algorithm
 	filePath := filepath.Join(up.WorkDir(), filename)
-	sha256, err := up.CalculateSHA256(filePath)
+	fileHash, err := up.CalculateHash(filePath)
 	if err != nil {
-		return fmt.Errorf("error calculating SHA256: %v", err)
+		return fmt.Errorf("error calculating %s: %v", up.HashAlgorithm(), err)
+	}
+
+	// Verify against the hash recorded for this file, if any
+	if err := verifyRecordedHash(up, led, filename, filePath, fileHash); err != nil {
+		return err
 	}
 
-	// Switch to the new version
-	err = up.SwitchToVersion(remoteVersion)
+	// Run the configured verifiers and switch to the new version
+	err = up.InstallVersion(updater.Metadata{
+		Version:       remoteVersion,
+		Filename:      filename,
+		HashAlgorithm: up.HashAlgorithm(),
+		Hash:          fileHash,
+	})
 	if err != nil {
-		return fmt.Errorf("error switching to version: %v", err)
+		var verifyErr *updater.VerificationError
+		if errors.As(err, &verifyErr) {
+			quarantineUnverified(up, led, remoteVersion, filePath, fileHash)
+			return fmt.Errorf("verification failed for %s: %v", filename, err)
+		}
+		return fmt.Errorf("error installing version: %v", err)
+	}
+
+	// Only share downloads that passed verification
+	if err := up.PopulateSharedCache(filename, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to populate shared cache: %v\n", err)
 	}
 
 	// Log the update
@@ -164,8 +309,10 @@ func executeUpdate(up *updater.Updater, led *ledger.Ledger, cfg *config.Config)
 		Version:    remoteVersion,
 		InternalID: "", // TODO: Extract from AppImage
 		Filename:   filename,
-		SHA256:     sha256,
+		SHA256:     fileHash,
 		Action:     "update",
+
+		HashAlgorithm: up.HashAlgorithm(),
 	}
 
 	if err := led.Append(entry); err != nil {
@@ -204,12 +351,12 @@ func executeForce(up *updater.Updater, led *ledger.Ledger, cfg *config.Config) e
 		fmt.Printf("🔄 Test mode: Simulating force download...\n")
 	}
 
-	up.SetProgressCallback(func(update updater.ProgressUpdate) {
-		displayProgress(update)
-	})
+	stopProgress := startProgress(up, cfg)
 
-	// Download the update
-	filename, err = up.DownloadCursor()
+	// Download the update, bypassing the shared cache in case its copy is
+	// the one being repaired
+	filename, err = up.Download(updater.DownloadOptions{Version: remoteVersion, SkipSharedCache: true})
+	stopProgress()
 	if err != nil {
 		return fmt.Errorf("error downloading Cursor: %v", err)
 	}
@@ -217,17 +364,35 @@ func executeForce(up *updater.Updater, led *ledger.Ledger, cfg *config.Config) e
 	// Add spacing after download completion
 	fmt.Println()
 
-	// Calculate SHA256
+	// Calculate hash with the configured algorithm
 	filePath = filepath.Join(up.WorkDir(), filename)
-	sha256, err := up.CalculateSHA256(filePath)
+	fileHash, err := up.CalculateHash(filePath)
 	if err != nil {
-		return fmt.Errorf("error calculating SHA256: %v", err)
+		return fmt.Errorf("error calculating %s: %v", up.HashAlgorithm(), err)
 	}
 
-	// Switch to the new version
-	err = up.SwitchToVersion(remoteVersion)
+	// force is how a bad download gets repaired, so it isn't checked against
+	// the hash recorded for the previous copy; the verifiers still run
+
+	// Run the configured verifiers and switch to the new version
+	err = up.InstallVersion(updater.Metadata{
+		Version:       remoteVersion,
+		Filename:      filename,
+		HashAlgorithm: up.HashAlgorithm(),
+		Hash:          fileHash,
+	})
 	if err != nil {
-		return fmt.Errorf("error switching to version: %v", err)
+		var verifyErr *updater.VerificationError
+		if errors.As(err, &verifyErr) {
+			quarantineUnverified(up, led, remoteVersion, filePath, fileHash)
+			return fmt.Errorf("verification failed for %s: %v", filename, err)
+		}
+		return fmt.Errorf("error installing version: %v", err)
+	}
+
+	// Only share downloads that passed verification, replacing any cached copy
+	if err := up.PopulateSharedCache(filename, true); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to populate shared cache: %v\n", err)
 	}
 
 	// Log the update
@@ -236,8 +401,10 @@ func executeForce(up *updater.Updater, led *ledger.Ledger, cfg *config.Config) e
 		Version:    remoteVersion,
 		InternalID: "", // TODO: Extract from AppImage
 		Filename:   filename,
-		SHA256:     sha256,
+		SHA256:     fileHash,
 		Action:     "force",
+
+		HashAlgorithm: up.HashAlgorithm(),
 	}
 
 	if err := led.Append(entry); err != nil {
@@ -251,20 +418,220 @@ func executeForce(up *updater.Updater, led *ledger.Ledger, cfg *config.Config) e
 	return nil
 }
 
-func executeList(led *ledger.Ledger) error {
-	entries, err := led.ReadAll()
+// verifyRecordedHash checks a downloaded file against the most recent hash
+// recorded in the ledger for the same filename, using the algorithm that was
+// recorded with it. Files that fail verification are quarantined when a
+// quarantine directory is configured and removed otherwise.
+func verifyRecordedHash(up *updater.Updater, led *ledger.Ledger, filename, filePath, fileHash string) error {
+	entries, err := led.FindByFilename(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to read ledger for verification: %v\n", err)
+		return nil
+	}
+
+	var recorded *ledger.Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].SHA256 != "" {
+			recorded = &entries[i]
+			break
+		}
+	}
+
+	if recorded == nil {
+		return nil
+	}
+
+	// Reuse the computed hash when the algorithms match
+	if recorded.Algorithm() == up.HashAlgorithm() {
+		if strings.EqualFold(recorded.SHA256, fileHash) {
+			return nil
+		}
+		err = fmt.Errorf("%s mismatch: expected %s, got %s", recorded.Algorithm(), recorded.SHA256, fileHash)
+	} else {
+		err = up.VerifyHash(filePath, recorded.Algorithm(), recorded.SHA256)
+		if err == nil {
+			return nil
+		}
+	}
+
+	quarantineUnverified(up, led, recorded.Version, filePath, fileHash)
+	return fmt.Errorf("verification failed for %s: %v", filename, err)
+}
+
+// quarantineUnverified moves a file that failed verification to the
+// quarantine directory and logs a quarantine entry, or removes the file when
+// no quarantine directory is configured, so it can't be installed later
+func quarantineUnverified(up *updater.Updater, led *ledger.Ledger, ver, filePath, fileHash string) {
+	quarantinePath, err := up.QuarantineFile(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to remove unverified file: %v\n", err)
+		return
+	}
+
+	if quarantinePath == "" {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Quarantined unverified file to %s\n", quarantinePath)
+
+	entry := ledger.Entry{
+		Timestamp:  time.Now(),
+		Version:    ver,
+		InternalID: "", // TODO: Extract from AppImage
+		Filename:   filepath.Base(quarantinePath),
+		SHA256:     fileHash,
+		Action:     "quarantine",
+
+		HashAlgorithm: up.HashAlgorithm(),
+	}
+
+	if err := led.Append(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to log quarantine: %v\n", err)
+	}
+}
+
+// Output formats supported by the list command
+const (
+	formatTable  = "table"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// listOptions controls which ledger entries are shown and in which order
+type listOptions struct {
+	Reverse      bool   // show newest entries first
+	Tail         int    // only show the last N entries in file order (0 = all)
+	Format       string // table, json or ndjson
+	SinceVersion string // only show entries for this version or newer
+}
+
+// include reports whether an entry passes the list filters. Entries whose
+// version can't be parsed are skipped when filtering by version.
+func (opts listOptions) include(entry ledger.Entry) bool {
+	if opts.SinceVersion == "" {
+		return true
+	}
+
+	if _, _, _, err := version.ParseSemver(entry.Version); err != nil {
+		return false
+	}
+
+	return !version.LessThan(entry.Version, opts.SinceVersion)
+}
+
+// parseListArgs parses the flags of the list command
+func parseListArgs(args []string) (listOptions, error) {
+	var opts listOptions
+
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.BoolVar(&opts.Reverse, "reverse", false, "show newest entries first")
+	fs.IntVar(&opts.Tail, "tail", 0, "only show the last N entries")
+	fs.StringVar(&opts.Format, "format", formatTable, "output format: table, json or ndjson")
+	fs.StringVar(&opts.SinceVersion, "since-version", "", "only show entries for this version or newer")
+
+	if err := fs.Parse(args); err != nil {
+		return listOptions{}, err
+	}
+
+	if opts.Tail < 0 {
+		return listOptions{}, fmt.Errorf("--tail must not be negative: %d", opts.Tail)
+	}
+
+	switch opts.Format {
+	case formatTable, formatJSON, formatNDJSON:
+	default:
+		return listOptions{}, fmt.Errorf("unknown format: %s (expected table, json or ndjson)", opts.Format)
+	}
+
+	if opts.SinceVersion != "" {
+		if _, _, _, err := version.ParseSemver(opts.SinceVersion); err != nil {
+			return listOptions{}, fmt.Errorf("invalid --since-version %s: %v", opts.SinceVersion, err)
+		}
+	}
+
+	return opts, nil
+}
+
+func executeList(w io.Writer, led *ledger.Ledger, opts listOptions) error {
+	// Stream ndjson straight from the ledger when no reordering is needed
+	if opts.Format == formatNDJSON && !opts.Reverse && opts.Tail == 0 {
+		encoder := json.NewEncoder(w)
+		err := led.Each(func(entry ledger.Entry) error {
+			if !opts.include(entry) {
+				return nil
+			}
+			return encoder.Encode(entry)
+		})
+		if err != nil {
+			return fmt.Errorf("error reading ledger: %v", err)
+		}
+		return nil
+	}
+
+	entries := []ledger.Entry{}
+	err := led.Each(func(entry ledger.Entry) error {
+		if opts.include(entry) {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("error reading ledger: %v", err)
 	}
 
+	// Tail selects the most recent entries in file order, reversing only
+	// changes how they are printed
+	if opts.Tail > 0 && opts.Tail < len(entries) {
+		entries = entries[len(entries)-opts.Tail:]
+	}
+
+	if opts.Reverse {
+		reversed := make([]ledger.Entry, len(entries))
+		for i, entry := range entries {
+			reversed[len(entries)-1-i] = entry
+		}
+		entries = reversed
+	}
+
+	switch opts.Format {
+	case formatJSON:
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding ledger: %v", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	case formatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("error encoding ledger: %v", err)
+			}
+		}
+		return nil
+	}
+
 	if len(entries) == 0 {
-		fmt.Println("No ledger entries found.")
+		fmt.Fprintln(w, "No ledger entries found.")
 		return nil
 	}
 
+	// Only show the user column when users were recorded
+	showUser := false
+	for _, entry := range entries {
+		if entry.User != "" {
+			showUser = true
+			break
+		}
+	}
+
 	// Print header
-	fmt.Printf("%-24s\t%-7s\t%-8s\t%-30s\t%-12s\t%s\n",
-		"when(UTC)", "ver", "internal", "file", "sha256 (short)", "action")
+	fmt.Fprintf(w, "%-24s\t%-7s\t%-8s\t%-30s\t%-12s\t%s",
+		"when(UTC)", "ver", "internal", "file", "hash (short)", "action")
+	if showUser {
+		fmt.Fprintf(w, "\t%s", "user")
+	}
+	fmt.Fprintln(w)
 
 	// Print entries
 	for _, entry := range entries {
@@ -273,37 +640,102 @@ func executeList(led *ledger.Ledger) error {
 			sha256Short = sha256Short[:12]
 		}
 
-		fmt.Printf("%-24s\t%-7s\t%-8s\t%-30s\t%-12s\t%s\n",
+		fmt.Fprintf(w, "%-24s\t%-7s\t%-8s\t%-30s\t%-12s\t%s",
 			entry.Timestamp.Format("2006-01-02 15:04:05"), entry.Version, entry.InternalID, entry.Filename, sha256Short, entry.Action)
+		if showUser {
+			fmt.Fprintf(w, "\t%s", entry.User)
+		}
+		fmt.Fprintln(w)
 	}
 
 	return nil
 }
 
-func executeSwitch(up *updater.Updater, led *ledger.Ledger, ver string, cfg *config.Config) error {
+// switchOptions controls how the switch command relinks a version
+type switchOptions struct {
+	NoLog           bool // relink without appending a ledger entry
+	FailOnDowngrade bool // refuse to switch to a version older than the current one
+	AllowDowngrade  bool // switch to an older version without asking
+
+	// Confirm asks the user to confirm a downgrade, nil means no confirmation
+	Confirm func(prompt string) bool
+}
+
+// parseSwitchArgs parses the version and flags of the switch command.
+// Flags may appear before or after the version.
+func parseSwitchArgs(args []string) (string, switchOptions, error) {
+	var opts switchOptions
+
+	fs := flag.NewFlagSet("switch", flag.ContinueOnError)
+	fs.BoolVar(&opts.NoLog, "no-log", false, "don't record the switch in the ledger")
+	fs.BoolVar(&opts.FailOnDowngrade, "fail-on-downgrade", false, "refuse to switch to an older version")
+	fs.BoolVar(&opts.AllowDowngrade, "allow-downgrade", false, "switch to an older version without asking")
+
+	if err := fs.Parse(args); err != nil {
+		return "", switchOptions{}, err
+	}
+
+	if fs.NArg() < 1 {
+		return "", switchOptions{}, fmt.Errorf("usage: %s switch [--no-log] [--fail-on-downgrade|--allow-downgrade] <version>", os.Args[0])
+	}
+	ver := fs.Arg(0)
+
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return "", switchOptions{}, err
+	}
+
+	if fs.NArg() > 0 {
+		return "", switchOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if opts.FailOnDowngrade && opts.AllowDowngrade {
+		return "", switchOptions{}, fmt.Errorf("--fail-on-downgrade and --allow-downgrade are mutually exclusive")
+	}
+
+	return ver, opts, nil
+}
+
+func executeSwitch(up *updater.Updater, led *ledger.Ledger, ver string, cfg *config.Config, opts switchOptions) error {
 	// Validate version format
 	if version.SemverFromName(fmt.Sprintf("Cursor-%s-x86_64.AppImage", ver)) == "" {
 		return fmt.Errorf("invalid version format: %s", ver)
 	}
 
+	// Guard against accidentally rolling back
+	currentVersion, err := up.ActiveVersion()
+	if err != nil {
+		return fmt.Errorf("error getting active version: %v", err)
+	}
+
+	if version.LessThan(ver, currentVersion) && !opts.AllowDowngrade {
+		if opts.FailOnDowngrade {
+			return fmt.Errorf("refusing to downgrade from %s to %s", currentVersion, ver)
+		}
+		if opts.Confirm != nil && !opts.Confirm(fmt.Sprintf("Switch from %s down to older version %s?", currentVersion, ver)) {
+			return fmt.Errorf("downgrade from %s to %s not confirmed (use --allow-downgrade)", currentVersion, ver)
+		}
+	}
+
 	// Switch to the specified version
-	err := up.SwitchToVersion(ver)
+	err = up.SwitchToVersion(ver)
 	if err != nil {
 		return fmt.Errorf("error switching to version: %v", err)
 	}
 
-	// Log the switch
-	entry := ledger.Entry{
-		Timestamp:  time.Now(),
-		Version:    ver,
-		InternalID: "", // TODO: Extract from AppImage
-		Filename:   up.GenerateFileName(ver),
-		SHA256:     "", // TODO: Calculate SHA256
-		Action:     "switch",
-	}
+	// Log the switch unless it's a transient experiment
+	if !opts.NoLog {
+		entry := ledger.Entry{
+			Timestamp:  time.Now(),
+			Version:    ver,
+			InternalID: "", // TODO: Extract from AppImage
+			Filename:   up.GenerateFileName(ver),
+			SHA256:     "", // TODO: Calculate SHA256
+			Action:     "switch",
+		}
 
-	if err := led.Append(entry); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to log switch: %v\n", err)
+		if err := led.Append(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to log switch: %v\n", err)
+		}
 	}
 
 	// Update version file
@@ -313,6 +745,160 @@ func executeSwitch(up *updater.Updater, led *ledger.Ledger, ver string, cfg *con
 	return nil
 }
 
+// confirmOnTerminal asks a yes/no question when stdin is a terminal.
+// Non-interactive runs can't answer, so they are refused and need explicit
+// consent through a flag instead.
+func confirmOnTerminal(prompt string) bool {
+	if !isTerminal(os.Stdin) {
+		fmt.Fprintf(os.Stderr, "%s Not confirmed: stdin is not a terminal\n", prompt)
+		return false
+	}
+
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// versionsOptions controls the versions command
+type versionsOptions struct {
+	Remote bool // also list versions available for download
+	JSON   bool // print JSON instead of a table
+}
+
+// versionsReport is the output of the versions command
+type versionsReport struct {
+	RemoteChecked bool                    `json:"remote_checked"`
+	RemoteError   string                  `json:"remote_error,omitempty"`
+	Versions      []updater.VersionStatus `json:"versions"`
+}
+
+// parseVersionsArgs parses the flags of the versions command
+func parseVersionsArgs(args []string) (versionsOptions, error) {
+	var opts versionsOptions
+
+	fs := flag.NewFlagSet("versions", flag.ContinueOnError)
+	fs.BoolVar(&opts.Remote, "remote", false, "also list versions available for download")
+	fs.BoolVar(&opts.JSON, "json", false, "print JSON instead of a table")
+
+	if err := fs.Parse(args); err != nil {
+		return versionsOptions{}, err
+	}
+
+	return opts, nil
+}
+
+func executeVersions(w io.Writer, up *updater.Updater, opts versionsOptions) error {
+	local, err := up.LocalVersions()
+	if err != nil {
+		return fmt.Errorf("error listing local versions: %v", err)
+	}
+
+	active, err := up.ActiveVersion()
+	if err != nil {
+		return fmt.Errorf("error getting active version: %v", err)
+	}
+
+	report := versionsReport{RemoteChecked: opts.Remote}
+
+	// Still show local state when the remote can't be reached
+	var remote []string
+	if opts.Remote {
+		remote, err = up.ListRemoteVersions()
+		if err != nil {
+			report.RemoteError = err.Error()
+			fmt.Fprintf(os.Stderr, "Warning: Could not list remote versions: %v\n", err)
+		}
+	}
+
+	report.Versions = updater.MergeVersions(remote, local, active)
+
+	if opts.JSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding versions: %v", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	if len(report.Versions) == 0 {
+		fmt.Fprintln(w, "No versions found.")
+		return nil
+	}
+
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	// Print header
+	fmt.Fprintf(w, "%-10s", "ver")
+	if report.RemoteChecked {
+		fmt.Fprintf(w, "\t%-6s", "remote")
+	}
+	fmt.Fprintf(w, "\t%-5s\t%s\n", "local", "active")
+
+	// Print versions, marking remote availability unknown when listing failed
+	for _, status := range report.Versions {
+		fmt.Fprintf(w, "%-10s", status.Version)
+		if report.RemoteChecked {
+			remote := yesNo(status.Remote)
+			if report.RemoteError != "" {
+				remote = "?"
+			}
+			fmt.Fprintf(w, "\t%-6s", remote)
+		}
+		active := ""
+		if status.Active {
+			active = "*"
+		}
+		fmt.Fprintf(w, "\t%-5s\t%s\n", yesNo(status.Local), active)
+	}
+
+	return nil
+}
+
+func executeRelink(up *updater.Updater) error {
+	result, err := up.Relink()
+	if err != nil {
+		return fmt.Errorf("error relinking: %v", err)
+	}
+
+	if !result.Changed {
+		fmt.Printf("Symlink already points to %s (%s)\n", result.NewTarget, result.Version)
+		return nil
+	}
+
+	fmt.Printf("Relinked %s: %s -> %s\n", result.Version, result.OldTarget, result.NewTarget)
+	return nil
+}
+
+func executeDedupe(up *updater.Updater) error {
+	result, err := up.Dedupe()
+	if err != nil {
+		return fmt.Errorf("error deduplicating versions: %v", err)
+	}
+
+	for _, name := range result.Linked {
+		fmt.Printf("Linked %s\n", name)
+	}
+
+	for _, name := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Warning: Skipped %s (different device)\n", name)
+	}
+
+	if len(result.Linked) == 0 {
+		fmt.Println("No duplicate versions found.")
+		return nil
+	}
+
+	fmt.Printf("Reclaimed %.1f MB\n", float64(result.Reclaimed)/(1024*1024))
+	return nil
+}
+
 func updateVersionFile(version string, cfg *config.Config) {
 	// Use config workDir for version file
 	workDir := cfg.DownloadDir
@@ -324,8 +910,71 @@ func updateVersionFile(version string, cfg *config.Config) {
 	}
 }
 
+// defaultProgressChars are the ASCII progress bar characters
+var defaultProgressChars = config.ProgressBarConfig{Fill: "=", Empty: " ", Head: ">"}
+
+// resolveProgressChars returns the configured progress bar characters, falling
+// back to ASCII when they need unicode and the locale doesn't support it
+func resolveProgressChars(cfg *config.Config, getenv func(string) string) config.ProgressBarConfig {
+	chars := cfg.ProgressBar
+	if chars.Fill == "" {
+		chars.Fill = defaultProgressChars.Fill
+	}
+	if chars.Empty == "" {
+		chars.Empty = defaultProgressChars.Empty
+	}
+
+	ascii := true
+	for _, c := range chars.Fill + chars.Empty + chars.Head {
+		if c > 127 {
+			ascii = false
+			break
+		}
+	}
+
+	if !ascii && !localeSupportsUnicode(getenv) {
+		return defaultProgressChars
+	}
+
+	return chars
+}
+
+// localeSupportsUnicode reports whether the locale uses a UTF-8 charset,
+// checking the same variables in the same order as setlocale
+func localeSupportsUnicode(getenv func(string) string) bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := getenv(name); value != "" {
+			value = strings.ToLower(value)
+			return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+		}
+	}
+	return false
+}
+
+// renderProgressBar draws a progress bar of the given width using chars
+func renderProgressBar(percentage float64, width int, chars config.ProgressBarConfig) string {
+	filled := int(float64(width) * percentage / 100)
+	if filled > width {
+		filled = width
+	}
+
+	var bar strings.Builder
+	bar.WriteString("[")
+	bar.WriteString(strings.Repeat(chars.Fill, filled))
+
+	empty := width - filled
+	if filled < width && chars.Head != "" {
+		bar.WriteString(chars.Head)
+		empty--
+	}
+	bar.WriteString(strings.Repeat(chars.Empty, empty))
+	bar.WriteString("]")
+
+	return bar.String()
+}
+
 // displayProgress shows a nice progress bar for downloads
-func displayProgress(update updater.ProgressUpdate) {
+func displayProgress(update updater.ProgressUpdate, chars config.ProgressBarConfig) {
 	if update.TotalBytes <= 0 {
 		return
 	}
@@ -337,22 +986,7 @@ func displayProgress(update updater.ProgressUpdate) {
 	}
 
 	// Create progress bar (50 characters wide)
-	barWidth := 50
-	filled := int(float64(barWidth) * percentage / 100)
-	empty := barWidth - filled
-
-	// Build progress bar string
-	bar := "["
-	for i := 0; i < filled; i++ {
-		bar += "="
-	}
-	if filled < barWidth {
-		bar += ">"
-	}
-	for i := 0; i < empty-1; i++ {
-		bar += " "
-	}
-	bar += "]"
+	bar := renderProgressBar(percentage, 50, chars)
 
 	// Format file sizes
 	downloadedMB := float64(update.BytesDownloaded) / (1024 * 1024)
@@ -375,9 +1009,23 @@ func showUsage() {
 Commands:
   check           Print local vs remote versions and exit with status (10=update needed)
   update          Download latest if newer and set symlink (default)
+                    --uptodate-exit-code N  exit status when already up to date
+                    --expected-version V    fail unless the remote latest is V
   force           Re-download latest even if it exists and relink
   list            Show ledger (configurable location)
+                    --reverse   show newest entries first
+                    --tail N    only show the last N entries
+                    --format F  output format: table, json or ndjson
+                    --since-version V  only show entries for V or newer
   switch <ver>    Point symlink at an existing version (no download)
+                    --no-log    don't record the switch in the ledger
+                    --fail-on-downgrade  refuse to switch to an older version
+                    --allow-downgrade    switch to an older version without asking
+  versions        Show downloaded versions and which one is active
+                    --remote    also show versions available for download
+                    --json      print JSON instead of a table
+  relink          Rewrite the symlink relative to the current download dir
+  dedupe          Hardlink byte-identical versions to reclaim disk space
 
 Configuration:
   Config file: ~/.config/updateCursor/config.yaml
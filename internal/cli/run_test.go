@@ -1,8 +1,22 @@
 package cli
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/CoGorm/updateCursor/internal/config"
+	"github.com/CoGorm/updateCursor/internal/ledger"
+	"github.com/CoGorm/updateCursor/internal/updater"
 )
 
 // Test configuration
@@ -108,3 +122,1123 @@ func TestHelpCommandQuick(t *testing.T) {
 		t.Errorf("Expected help command to work, got: %v", err)
 	}
 }
+
+// newTestLedger creates a ledger in a temp dir with one entry per version
+func newTestLedger(t *testing.T, versions ...string) *ledger.Ledger {
+	t.Helper()
+
+	led := ledger.NewLedger(filepath.Join(t.TempDir(), "cursor-versions.log"))
+	for i, ver := range versions {
+		entry := ledger.Entry{
+			Timestamp: time.Date(2024, 1, 1+i, 12, 0, 0, 0, time.UTC),
+			Version:   ver,
+			Filename:  "Cursor-" + ver + "-x86_64.AppImage",
+			Action:    "update",
+		}
+		if err := led.Append(entry); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	return led
+}
+
+// listedVersions returns the version column of each entry line printed by list
+func listedVersions(output string) []string {
+	var versions []string
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		versions = append(versions, strings.TrimSpace(fields[1]))
+	}
+	return versions
+}
+
+func TestListReverse(t *testing.T) {
+	led := newTestLedger(t, "1.0.0", "1.1.0", "1.2.0")
+
+	tests := []struct {
+		name     string
+		opts     listOptions
+		expected []string
+	}{
+		{name: "oldest first by default", opts: listOptions{}, expected: []string{"1.0.0", "1.1.0", "1.2.0"}},
+		{name: "reverse", opts: listOptions{Reverse: true}, expected: []string{"1.2.0", "1.1.0", "1.0.0"}},
+		{name: "tail", opts: listOptions{Tail: 2}, expected: []string{"1.1.0", "1.2.0"}},
+		{name: "reverse with tail", opts: listOptions{Reverse: true, Tail: 2}, expected: []string{"1.2.0", "1.1.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := executeList(&buf, led, tt.opts); err != nil {
+				t.Fatalf("Failed to list entries: %v", err)
+			}
+
+			got := listedVersions(buf.String())
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected order %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// Reversing the output must not change the order on disk
+	entries, err := led.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read ledger: %v", err)
+	}
+
+	if entries[0].Version != "1.0.0" {
+		t.Errorf("Expected ledger file to stay oldest first, got %s first", entries[0].Version)
+	}
+}
+
+func TestParseListArgs(t *testing.T) {
+	opts, err := parseListArgs([]string{"--reverse", "--tail", "5"})
+	if err != nil {
+		t.Fatalf("Failed to parse list args: %v", err)
+	}
+
+	if !opts.Reverse || opts.Tail != 5 {
+		t.Errorf("Expected reverse with tail 5, got %+v", opts)
+	}
+
+	if _, err := parseListArgs([]string{"--tail", "-1"}); err == nil {
+		t.Error("Expected error for negative tail")
+	}
+
+	if _, err := parseListArgs([]string{"--format", "xml"}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+
+	if _, err := parseListArgs([]string{"--since-version", "latest"}); err == nil {
+		t.Error("Expected error for invalid since version")
+	}
+}
+
+func TestListSinceVersion(t *testing.T) {
+	led := newTestLedger(t, "1.0.0", "1.2.0", "unknown", "1.1.9", "1.3.0", "2.0.0")
+
+	tests := []struct {
+		name     string
+		opts     listOptions
+		expected []string
+	}{
+		{name: "since", opts: listOptions{SinceVersion: "1.2.0"}, expected: []string{"1.2.0", "1.3.0", "2.0.0"}},
+		{name: "since with reverse and tail", opts: listOptions{SinceVersion: "1.2.0", Reverse: true, Tail: 2}, expected: []string{"2.0.0", "1.3.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := executeList(&buf, led, tt.opts); err != nil {
+				t.Fatalf("Failed to list entries: %v", err)
+			}
+
+			got := listedVersions(buf.String())
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected versions %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// Streaming ndjson applies the same filter
+	var buf bytes.Buffer
+	if err := executeList(&buf, led, listOptions{SinceVersion: "1.2.0", Format: formatNDJSON}); err != nil {
+		t.Fatalf("Failed to list entries: %v", err)
+	}
+
+	var versions []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry ledger.Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Line is not valid JSON: %v (%q)", err, line)
+		}
+		versions = append(versions, entry.Version)
+	}
+
+	if strings.Join(versions, ",") != "1.2.0,1.3.0,2.0.0" {
+		t.Errorf("Expected streamed versions 1.2.0,1.3.0,2.0.0, got %v", versions)
+	}
+}
+
+func TestListNDJSON(t *testing.T) {
+	led := newTestLedger(t, "1.0.0", "1.1.0", "1.2.0")
+
+	entries, err := led.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read ledger: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     listOptions
+		expected []ledger.Entry
+	}{
+		{name: "streamed", opts: listOptions{Format: formatNDJSON}, expected: entries},
+		{name: "reverse with tail", opts: listOptions{Format: formatNDJSON, Reverse: true, Tail: 2}, expected: []ledger.Entry{entries[2], entries[1]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := executeList(&buf, led, tt.opts); err != nil {
+				t.Fatalf("Failed to list entries: %v", err)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != len(tt.expected) {
+				t.Fatalf("Expected %d lines, got %d: %q", len(tt.expected), len(lines), buf.String())
+			}
+
+			// Each line must be a complete JSON object on its own
+			for i, line := range lines {
+				var entry ledger.Entry
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("Line %d is not valid JSON: %v (%q)", i, err, line)
+				}
+				if !entry.Timestamp.Equal(tt.expected[i].Timestamp) || entry.Version != tt.expected[i].Version ||
+					entry.Filename != tt.expected[i].Filename || entry.Action != tt.expected[i].Action {
+					t.Errorf("Line %d: expected %+v, got %+v", i, tt.expected[i], entry)
+				}
+			}
+		})
+	}
+}
+
+func TestListJSON(t *testing.T) {
+	led := newTestLedger(t, "1.0.0", "1.1.0")
+
+	var buf bytes.Buffer
+	if err := executeList(&buf, led, listOptions{Format: formatJSON}); err != nil {
+		t.Fatalf("Failed to list entries: %v", err)
+	}
+
+	var entries []ledger.Entry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("Expected a JSON array, got error: %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].Version != "1.0.0" || entries[1].Version != "1.1.0" {
+		t.Errorf("Expected entries 1.0.0 and 1.1.0, got %+v", entries)
+	}
+
+	// An empty ledger is still a valid array
+	buf.Reset()
+	if err := executeList(&buf, newTestLedger(t), listOptions{Format: formatJSON}); err != nil {
+		t.Fatalf("Failed to list empty ledger: %v", err)
+	}
+
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("Expected empty JSON array, got %q", buf.String())
+	}
+}
+
+func TestVerifyRecordedHash(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cfg := config.NewConfig()
+	cfg.DownloadDir = tempDir
+	cfg.HashAlgorithm = config.HashSHA512
+	up := updater.NewUpdater("http://example.com", tempDir, cfg)
+
+	filename := "Cursor-1.0.0-x86_64.AppImage"
+	filePath := filepath.Join(tempDir, filename)
+	if err := os.WriteFile(filePath, []byte("Hello, World!"), 0755); err != nil {
+		t.Fatalf("Failed to create version file: %v", err)
+	}
+
+	fileHash, err := up.CalculateHash(filePath)
+	if err != nil {
+		t.Fatalf("Failed to calculate hash: %v", err)
+	}
+
+	led := ledger.NewLedger(filepath.Join(tempDir, "cursor-versions.log"))
+
+	// Nothing recorded yet, so nothing to verify against
+	if err := verifyRecordedHash(up, led, filename, filePath, fileHash); err != nil {
+		t.Errorf("Expected no error without recorded hash, got: %v", err)
+	}
+
+	// A sha256 hash recorded earlier is verified with sha256
+	led.Append(ledger.Entry{
+		Timestamp:     time.Now(),
+		Version:       "1.0.0",
+		Filename:      filename,
+		SHA256:        "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f",
+		Action:        "update",
+		HashAlgorithm: config.HashSHA256,
+	})
+	if err := verifyRecordedHash(up, led, filename, filePath, fileHash); err != nil {
+		t.Errorf("Expected recorded sha256 hash to verify, got: %v", err)
+	}
+
+	// A mismatching hash under the configured algorithm fails and removes the file
+	led.Append(ledger.Entry{
+		Timestamp:     time.Now(),
+		Version:       "1.0.0",
+		Filename:      filename,
+		SHA256:        strings.Repeat("0", len(fileHash)),
+		Action:        "update",
+		HashAlgorithm: config.HashSHA512,
+	})
+	if err := verifyRecordedHash(up, led, filename, filePath, fileHash); err == nil {
+		t.Error("Expected verification to fail for mismatching hash")
+	}
+
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Error("Expected unverified file to be removed")
+	}
+}
+
+// newTestSetup creates a config, updater and ledger rooted in a temp dir
+// with a version file for each of the given versions
+func newTestSetup(t *testing.T, serverURL string, versions ...string) (*config.Config, *updater.Updater, *ledger.Ledger) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+
+	cfg := config.NewConfig()
+	cfg.DownloadDir = filepath.Join(tempDir, "downloads")
+	cfg.LatestSymlink = filepath.Join(cfg.DownloadDir, "Cursor.AppImage")
+	cfg.LedgerPath = filepath.Join(tempDir, "config", "cursor-versions.log")
+
+	if err := os.MkdirAll(cfg.DownloadDir, 0755); err != nil {
+		t.Fatalf("Failed to create download dir: %v", err)
+	}
+
+	for _, ver := range versions {
+		path := filepath.Join(cfg.DownloadDir, cfg.GenerateFileName(ver))
+		if err := os.WriteFile(path, []byte("mock content "+ver), 0755); err != nil {
+			t.Fatalf("Failed to create version file: %v", err)
+		}
+	}
+
+	up := updater.NewUpdater(serverURL, cfg.DownloadDir, cfg)
+	led := ledger.NewLedger(cfg.LedgerPath)
+
+	return cfg, up, led
+}
+
+func TestSwitchNoLog(t *testing.T) {
+	cfg, up, led := newTestSetup(t, "http://example.com", "1.0.0", "1.1.0")
+
+	// A logged switch writes a ledger entry
+	if err := executeSwitch(up, led, "1.0.0", cfg, switchOptions{}); err != nil {
+		t.Fatalf("Failed to switch: %v", err)
+	}
+
+	// A --no-log switch still relinks but leaves the ledger alone
+	if err := executeSwitch(up, led, "1.1.0", cfg, switchOptions{NoLog: true}); err != nil {
+		t.Fatalf("Failed to switch with --no-log: %v", err)
+	}
+
+	target, err := os.Readlink(cfg.LatestSymlink)
+	if err != nil {
+		t.Fatalf("Failed to read symlink: %v", err)
+	}
+
+	if target != "Cursor-1.1.0-x86_64.AppImage" {
+		t.Errorf("Expected symlink to point to Cursor-1.1.0-x86_64.AppImage, got %s", target)
+	}
+
+	entries, err := led.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read ledger: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Version != "1.0.0" {
+		t.Errorf("Expected only the logged switch to 1.0.0 in the ledger, got %+v", entries)
+	}
+}
+
+func TestParseSwitchArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		noLog bool
+	}{
+		{args: []string{"1.4.5"}, noLog: false},
+		{args: []string{"--no-log", "1.4.5"}, noLog: true},
+		{args: []string{"1.4.5", "--no-log"}, noLog: true},
+	}
+
+	for _, tt := range tests {
+		ver, opts, err := parseSwitchArgs(tt.args)
+		if err != nil {
+			t.Fatalf("Failed to parse switch args %v: %v", tt.args, err)
+		}
+		if ver != "1.4.5" || opts.NoLog != tt.noLog {
+			t.Errorf("parseSwitchArgs(%v) = %s %+v, want 1.4.5 with no-log %v", tt.args, ver, opts, tt.noLog)
+		}
+	}
+
+	if _, _, err := parseSwitchArgs([]string{"--no-log"}); err == nil {
+		t.Error("Expected error for switch without version")
+	}
+
+	if _, _, err := parseSwitchArgs([]string{"--fail-on-downgrade", "--allow-downgrade", "1.4.5"}); err == nil {
+		t.Error("Expected error for conflicting downgrade flags")
+	}
+}
+
+// newTestServer serves a single Cursor version through the usual redirect
+func newTestServer(t *testing.T, ver, content string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/stable/linux-x64":
+			http.Redirect(w, r, "/download/Cursor-"+ver+"-x86_64.AppImage", http.StatusFound)
+		case "/download/Cursor-" + ver + "-x86_64.AppImage":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(content))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestUpdateQuarantinesMismatchedDownload(t *testing.T) {
+	server := newTestServer(t, "1.0.0", "tampered content")
+	cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64")
+	cfg.QuarantineDir = filepath.Join(t.TempDir(), "quarantine")
+
+	// The hash recorded when 1.0.0 was first downloaded
+	led.Append(ledger.Entry{
+		Timestamp:     time.Now(),
+		Version:       "1.0.0",
+		Filename:      "Cursor-1.0.0-x86_64.AppImage",
+		SHA256:        "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f",
+		Action:        "update",
+		HashAlgorithm: config.HashSHA256,
+	})
+
+	if err := executeUpdate(up, led, cfg, updateOptions{}); err == nil {
+		t.Fatal("Expected update to fail for a mismatched download")
+	}
+
+	// The bad file is moved out of the download dir
+	if _, err := os.Stat(filepath.Join(cfg.DownloadDir, "Cursor-1.0.0-x86_64.AppImage")); !os.IsNotExist(err) {
+		t.Error("Expected mismatched download to be removed from the download dir")
+	}
+
+	if _, err := os.Lstat(cfg.LatestSymlink); !os.IsNotExist(err) {
+		t.Error("Expected no symlink to a mismatched download")
+	}
+
+	// ...into the quarantine dir under a timestamped name
+	files, err := os.ReadDir(cfg.QuarantineDir)
+	if err != nil {
+		t.Fatalf("Failed to read quarantine dir: %v", err)
+	}
+
+	if len(files) != 1 || !strings.HasPrefix(files[0].Name(), "Cursor-1.0.0-x86_64.AppImage.") {
+		t.Fatalf("Expected one quarantined Cursor-1.0.0-x86_64.AppImage, got %v", files)
+	}
+
+	content, err := os.ReadFile(filepath.Join(cfg.QuarantineDir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("Failed to read quarantined file: %v", err)
+	}
+
+	if string(content) != "tampered content" {
+		t.Errorf("Expected quarantined file to keep served content, got %q", string(content))
+	}
+
+	// ...and the quarantine is recorded in the ledger
+	entries, err := led.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read ledger: %v", err)
+	}
+
+	last := entries[len(entries)-1]
+	if last.Action != "quarantine" || last.Filename != files[0].Name() || last.Version != "1.0.0" {
+		t.Errorf("Expected quarantine ledger entry for %s, got %+v", files[0].Name(), last)
+	}
+}
+
+func TestForceIgnoresRecordedHash(t *testing.T) {
+	server := newTestServer(t, "1.0.0", "rebuilt content")
+	cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64")
+	cfg.QuarantineDir = filepath.Join(t.TempDir(), "quarantine")
+
+	// A hash recorded for an earlier, different copy of 1.0.0
+	led.Append(ledger.Entry{
+		Timestamp:     time.Now(),
+		Version:       "1.0.0",
+		Filename:      "Cursor-1.0.0-x86_64.AppImage",
+		SHA256:        "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f",
+		Action:        "update",
+		HashAlgorithm: config.HashSHA256,
+	})
+
+	// force repairs downloads, so it must not be held to the old hash
+	if err := executeForce(up, led, cfg); err != nil {
+		t.Fatalf("Expected force to replace the download, got: %v", err)
+	}
+
+	content, err := os.ReadFile(cfg.LatestSymlink)
+	if err != nil {
+		t.Fatalf("Failed to read through symlink: %v", err)
+	}
+
+	if string(content) != "rebuilt content" {
+		t.Errorf("Expected the symlink to point at the new download, got %q", string(content))
+	}
+
+	entries, err := led.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read ledger: %v", err)
+	}
+
+	last := entries[len(entries)-1]
+	expectedHash, err := up.CalculateHash(cfg.LatestSymlink)
+	if err != nil {
+		t.Fatalf("Failed to hash download: %v", err)
+	}
+
+	if last.Action != "force" || last.SHA256 != expectedHash {
+		t.Errorf("Expected a force entry recording the new hash, got %+v", last)
+	}
+}
+
+func TestForceReplacesSharedCacheCopy(t *testing.T) {
+	server := newTestServer(t, "1.0.0", "fresh content")
+	cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64")
+	cfg.SharedCacheDir = filepath.Join(t.TempDir(), "shared")
+	cfg.SharedCachePopulate = true
+
+	// A bad copy that still matches its recorded hash
+	filename := cfg.GenerateFileName("1.0.0")
+	cachedPath := filepath.Join(cfg.SharedCacheDir, filename)
+	if err := os.MkdirAll(cfg.SharedCacheDir, 0755); err != nil {
+		t.Fatalf("Failed to create shared cache: %v", err)
+	}
+	if err := os.WriteFile(cachedPath, []byte("bad content"), 0755); err != nil {
+		t.Fatalf("Failed to write cached file: %v", err)
+	}
+	badSum := sha256.Sum256([]byte("bad content"))
+	if err := os.WriteFile(cachedPath+".sha256", []byte(hex.EncodeToString(badSum[:])+"  "+filename+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write cached hash: %v", err)
+	}
+
+	if err := executeForce(up, led, cfg); err != nil {
+		t.Fatalf("Expected force to succeed, got: %v", err)
+	}
+
+	installed, err := os.ReadFile(filepath.Join(cfg.DownloadDir, filename))
+	if err != nil {
+		t.Fatalf("Failed to read installed file: %v", err)
+	}
+	if string(installed) != "fresh content" {
+		t.Errorf("Expected force to install the downloaded copy, got %q", string(installed))
+	}
+
+	cached, err := os.ReadFile(cachedPath)
+	if err != nil {
+		t.Fatalf("Failed to read cached file: %v", err)
+	}
+	if string(cached) != "fresh content" {
+		t.Errorf("Expected force to replace the shared cache copy, got %q", string(cached))
+	}
+
+	recorded, err := os.ReadFile(cachedPath + ".sha256")
+	if err != nil {
+		t.Fatalf("Failed to read cached hash: %v", err)
+	}
+	freshSum := sha256.Sum256([]byte("fresh content"))
+	if !strings.HasPrefix(string(recorded), hex.EncodeToString(freshSum[:])+" ") {
+		t.Errorf("Expected the cached hash to be replaced, got %q", string(recorded))
+	}
+}
+
+func TestUpdateUpToDateExitCode(t *testing.T) {
+	server := newTestServer(t, "1.0.0", "mock content 1.0.0")
+
+	tests := []struct {
+		name       string
+		configCode int
+		args       []string
+		expected   int
+	}{
+		{name: "default", configCode: 0, expected: 0},
+		{name: "from config", configCode: 3, expected: 3},
+		{name: "flag overrides config", configCode: 3, args: []string{"--uptodate-exit-code", "7"}, expected: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64", "1.0.0")
+			cfg.UpToDateExitCode = tt.configCode
+
+			if err := up.SwitchToVersion("1.0.0"); err != nil {
+				t.Fatalf("Failed to switch to version: %v", err)
+			}
+
+			opts, err := parseUpdateArgs(tt.args, cfg)
+			if err != nil {
+				t.Fatalf("Failed to parse update args: %v", err)
+			}
+
+			err = executeUpdate(up, led, cfg, opts)
+
+			if tt.expected == 0 {
+				if err != nil {
+					t.Errorf("Expected no error when up to date, got: %v", err)
+				}
+				return
+			}
+
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("Expected ExitError, got: %v", err)
+			}
+
+			if exitErr.Code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, exitErr.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateExpectedVersion(t *testing.T) {
+	server := newTestServer(t, "1.1.0", "mock content 1.1.0")
+
+	t.Run("matching", func(t *testing.T) {
+		cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64")
+
+		opts, err := parseUpdateArgs([]string{"--expected-version", "1.1.0"}, cfg)
+		if err != nil {
+			t.Fatalf("Failed to parse update args: %v", err)
+		}
+
+		if err := executeUpdate(up, led, cfg, opts); err != nil {
+			t.Fatalf("Expected update to proceed, got: %v", err)
+		}
+
+		localVersion, err := up.GetLocalVersion()
+		if err != nil {
+			t.Fatalf("Failed to get local version: %v", err)
+		}
+
+		if localVersion != "1.1.0" {
+			t.Errorf("Expected local version 1.1.0, got %s", localVersion)
+		}
+	})
+
+	t.Run("mismatching", func(t *testing.T) {
+		cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64")
+
+		opts, err := parseUpdateArgs([]string{"--expected-version", "1.0.0"}, cfg)
+		if err != nil {
+			t.Fatalf("Failed to parse update args: %v", err)
+		}
+
+		err = executeUpdate(up, led, cfg, opts)
+		if err == nil || !strings.Contains(err.Error(), "1.0.0") {
+			t.Fatalf("Expected mismatch error mentioning 1.0.0, got: %v", err)
+		}
+
+		// Nothing may be downloaded or linked
+		files, err := os.ReadDir(cfg.DownloadDir)
+		if err != nil {
+			t.Fatalf("Failed to read download dir: %v", err)
+		}
+
+		if len(files) != 0 {
+			t.Errorf("Expected no downloads for a mismatched version, got %v", files)
+		}
+	})
+}
+
+func TestUpdateExpectedVersionRemoteMovesDuringDownload(t *testing.T) {
+	// The first check sees 1.1.0, every later request already sees 1.2.0
+	checks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/stable/linux-x64":
+			checks++
+			ver := "1.2.0"
+			if checks == 1 {
+				ver = "1.1.0"
+			}
+			http.Redirect(w, r, "/download/Cursor-"+ver+"-x86_64.AppImage", http.StatusFound)
+		case "/download/Cursor-1.1.0-x86_64.AppImage", "/download/Cursor-1.2.0-x86_64.AppImage":
+			w.Write([]byte("mock content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64")
+
+	err := executeUpdate(up, led, cfg, updateOptions{ExpectedVersion: "1.1.0"})
+	if err == nil || !strings.Contains(err.Error(), "1.1.0") {
+		t.Fatalf("Expected a mismatch error for the pinned version, got: %v", err)
+	}
+
+	if _, err := os.Lstat(cfg.LatestSymlink); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be installed, lstat returned: %v", err)
+	}
+
+	// Neither version may be left behind unrecorded
+	files, err := os.ReadDir(cfg.DownloadDir)
+	if err != nil {
+		t.Fatalf("Failed to read download dir: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected an empty download dir, got %d entries", len(files))
+	}
+
+	entries, err := led.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read ledger: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("Expected no ledger entries, got %v", entries)
+	}
+}
+
+func TestUpdateExpectedVersionBypassesCheckCache(t *testing.T) {
+	remoteVersion := "1.0.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/download/stable/linux-x64":
+			http.Redirect(w, r, "/download/Cursor-"+remoteVersion+"-x86_64.AppImage", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/download/Cursor-"):
+			w.Write([]byte("mock content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64", "1.0.0")
+	up.SetCheckCache(filepath.Join(filepath.Dir(cfg.LedgerPath), checkCacheFile), time.Hour)
+
+	if err := up.SwitchToVersion("1.0.0"); err != nil {
+		t.Fatalf("Failed to switch: %v", err)
+	}
+
+	// Cache a check that saw 1.0.0, then release 1.1.0
+	if _, err := up.GetRemoteVersionCached(); err != nil {
+		t.Fatalf("Failed to get remote version: %v", err)
+	}
+	remoteVersion = "1.1.0"
+
+	if err := executeUpdate(up, led, cfg, updateOptions{ExpectedVersion: "1.1.0"}); err != nil {
+		t.Fatalf("Expected the pinned update to see the live remote version, got: %v", err)
+	}
+
+	activeVersion, err := up.ActiveVersion()
+	if err != nil {
+		t.Fatalf("Failed to get active version: %v", err)
+	}
+
+	if activeVersion != "1.1.0" {
+		t.Errorf("Expected active version 1.1.0, got %s", activeVersion)
+	}
+}
+
+func TestRenderProgressBar(t *testing.T) {
+	tests := []struct {
+		name       string
+		percentage float64
+		chars      config.ProgressBarConfig
+		expected   string
+	}{
+		{name: "ascii default", percentage: 40, chars: defaultProgressChars, expected: "[====>     ]"},
+		{name: "ascii complete", percentage: 100, chars: defaultProgressChars, expected: "[==========]"},
+		{name: "unicode blocks", percentage: 40, chars: config.ProgressBarConfig{Fill: "█", Empty: "░"}, expected: "[████░░░░░░]"},
+		{name: "unicode with head", percentage: 40, chars: config.ProgressBarConfig{Fill: "━", Empty: "─", Head: "╸"}, expected: "[━━━━╸─────]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bar := renderProgressBar(tt.percentage, 10, tt.chars)
+			if bar != tt.expected {
+				t.Errorf("Expected bar %q, got %q", tt.expected, bar)
+			}
+		})
+	}
+}
+
+func TestResolveProgressChars(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.ProgressBar = config.ProgressBarConfig{Fill: "█", Empty: "░"}
+
+	env := func(values map[string]string) func(string) string {
+		return func(name string) string { return values[name] }
+	}
+
+	// A UTF-8 locale keeps the configured characters
+	chars := resolveProgressChars(cfg, env(map[string]string{"LANG": "en_US.UTF-8"}))
+	if chars != cfg.ProgressBar {
+		t.Errorf("Expected configured characters %+v, got %+v", cfg.ProgressBar, chars)
+	}
+
+	// LC_ALL takes precedence over LANG
+	chars = resolveProgressChars(cfg, env(map[string]string{"LC_ALL": "C", "LANG": "en_US.UTF-8"}))
+	if chars != defaultProgressChars {
+		t.Errorf("Expected ASCII fallback for C locale, got %+v", chars)
+	}
+
+	// No locale at all falls back to ASCII
+	chars = resolveProgressChars(cfg, env(map[string]string{}))
+	if chars != defaultProgressChars {
+		t.Errorf("Expected ASCII fallback without a locale, got %+v", chars)
+	}
+
+	// ASCII characters are used regardless of locale
+	cfg.ProgressBar = config.ProgressBarConfig{Fill: "#", Empty: "."}
+	chars = resolveProgressChars(cfg, env(map[string]string{}))
+	if chars != cfg.ProgressBar {
+		t.Errorf("Expected configured ASCII characters %+v, got %+v", cfg.ProgressBar, chars)
+	}
+}
+
+func TestSwitchDowngradeGuard(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		opts       switchOptions
+		expectErr  bool
+		expectAsk  bool
+		confirmRet bool
+	}{
+		{name: "fail on downgrade", target: "1.0.0", opts: switchOptions{FailOnDowngrade: true}, expectErr: true},
+		{name: "downgrade declined", target: "1.0.0", expectErr: true, expectAsk: true, confirmRet: false},
+		{name: "downgrade confirmed", target: "1.0.0", expectErr: false, expectAsk: true, confirmRet: true},
+		{name: "upgrade not guarded", target: "1.2.0", opts: switchOptions{FailOnDowngrade: true}, expectErr: false},
+		{name: "downgrade allowed", target: "1.0.0", opts: switchOptions{AllowDowngrade: true}, expectErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, up, led := newTestSetup(t, "http://example.com", "1.0.0", "1.1.0", "1.2.0")
+
+			if err := up.SwitchToVersion("1.1.0"); err != nil {
+				t.Fatalf("Failed to switch to version: %v", err)
+			}
+
+			asked := false
+			opts := tt.opts
+			opts.Confirm = func(prompt string) bool {
+				asked = true
+				return tt.confirmRet
+			}
+
+			err := executeSwitch(up, led, tt.target, cfg, opts)
+			if tt.expectErr && err == nil {
+				t.Fatal("Expected the downgrade guard to trigger")
+			}
+			if !tt.expectErr && err != nil {
+				t.Fatalf("Expected switch to succeed, got: %v", err)
+			}
+
+			if asked != tt.expectAsk {
+				t.Errorf("Expected confirmation asked to be %v, got %v", tt.expectAsk, asked)
+			}
+
+			expectedVersion := tt.target
+			if tt.expectErr {
+				expectedVersion = "1.1.0"
+			}
+
+			activeVersion, err := up.ActiveVersion()
+			if err != nil {
+				t.Fatalf("Failed to get active version: %v", err)
+			}
+
+			if activeVersion != expectedVersion {
+				t.Errorf("Expected active version %s, got %s", expectedVersion, activeVersion)
+			}
+		})
+	}
+}
+
+func TestSwitchDowngradeGuardWithSymlinkOutsideDownloadDir(t *testing.T) {
+	// A launcher in another directory and a custom pattern, as in config.example.yaml
+	cfg, _, led := newTestSetup(t, "http://example.com")
+	cfg.FileNamePattern = "Cursor_<version>.AppImage"
+	cfg.LatestSymlink = filepath.Join(t.TempDir(), "bin", "Cursor.AppImage")
+
+	for _, ver := range []string{"1.0.0", "1.1.0"} {
+		if err := os.WriteFile(filepath.Join(cfg.DownloadDir, cfg.GenerateFileName(ver)), []byte("mock content "+ver), 0755); err != nil {
+			t.Fatalf("Failed to create version file: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.LatestSymlink), 0755); err != nil {
+		t.Fatalf("Failed to create symlink dir: %v", err)
+	}
+
+	up := updater.NewUpdater("http://example.com", cfg.DownloadDir, cfg)
+	if err := up.SwitchToVersion("1.1.0"); err != nil {
+		t.Fatalf("Failed to switch to version: %v", err)
+	}
+
+	err := executeSwitch(up, led, "1.0.0", cfg, switchOptions{FailOnDowngrade: true})
+	if err == nil || !strings.Contains(err.Error(), "refusing to downgrade from 1.1.0") {
+		t.Fatalf("Expected the downgrade guard to trigger, got: %v", err)
+	}
+
+	asked := false
+	err = executeSwitch(up, led, "1.0.0", cfg, switchOptions{Confirm: func(string) bool {
+		asked = true
+		return false
+	}})
+	if err == nil || !asked {
+		t.Errorf("Expected the downgrade to need confirmation, got asked=%v err=%v", asked, err)
+	}
+}
+
+func TestRecordUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "enabled", enabled: true},
+		{name: "disabled", enabled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, up, _ := newTestSetup(t, "http://example.com", "1.0.0")
+			cfg.RecordUser = tt.enabled
+			led := newLedger(cfg.LedgerPath, cfg)
+
+			if err := executeSwitch(up, led, "1.0.0", cfg, switchOptions{}); err != nil {
+				t.Fatalf("Failed to switch: %v", err)
+			}
+
+			entries, err := led.ReadAll()
+			if err != nil {
+				t.Fatalf("Failed to read ledger: %v", err)
+			}
+
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(entries))
+			}
+
+			var buf bytes.Buffer
+			if err := executeList(&buf, led, listOptions{}); err != nil {
+				t.Fatalf("Failed to list entries: %v", err)
+			}
+			header := strings.SplitN(buf.String(), "\n", 2)[0]
+
+			if !tt.enabled {
+				if entries[0].User != "" {
+					t.Errorf("Expected no user recorded, got %s", entries[0].User)
+				}
+				if strings.HasSuffix(header, "user") {
+					t.Errorf("Expected no user column, got header %q", header)
+				}
+				return
+			}
+
+			if entries[0].User == "" || entries[0].User != currentUsername() {
+				t.Errorf("Expected user %q recorded, got %q", currentUsername(), entries[0].User)
+			}
+
+			if !strings.HasSuffix(header, "\tuser") || !strings.Contains(buf.String(), "\t"+entries[0].User+"\n") {
+				t.Errorf("Expected list to show user %s, got %q", entries[0].User, buf.String())
+			}
+		})
+	}
+}
+
+func TestVersionsRemote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/versions.json" {
+			w.Write([]byte(`["1.1.0", "1.2.0"]`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	cfg, up, _ := newTestSetup(t, server.URL+"/download/stable/linux-x64", "1.0.0", "1.1.0")
+	cfg.VersionsURL = server.URL + "/versions.json"
+
+	if err := up.SwitchToVersion("1.1.0"); err != nil {
+		t.Fatalf("Failed to switch: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := executeVersions(&buf, up, versionsOptions{Remote: true, JSON: true}); err != nil {
+		t.Fatalf("Failed to list versions: %v", err)
+	}
+
+	var report versionsReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	expected := []updater.VersionStatus{
+		{Version: "1.0.0", Local: true},
+		{Version: "1.1.0", Remote: true, Local: true, Active: true},
+		{Version: "1.2.0", Remote: true},
+	}
+	if !report.RemoteChecked || report.RemoteError != "" {
+		t.Errorf("Expected a successful remote check, got %+v", report)
+	}
+	if len(report.Versions) != len(expected) {
+		t.Fatalf("Expected %d versions, got %+v", len(expected), report.Versions)
+	}
+	for i, status := range expected {
+		if report.Versions[i] != status {
+			t.Errorf("Expected %+v, got %+v", status, report.Versions[i])
+		}
+	}
+}
+
+func TestVersionsActiveWithCustomPattern(t *testing.T) {
+	cfg, _, _ := newTestSetup(t, "http://example.com")
+	cfg.FileNamePattern = "Cursor_<version>.AppImage"
+	cfg.LatestSymlink = filepath.Join(t.TempDir(), "Cursor.AppImage")
+
+	for _, ver := range []string{"1.0.0", "1.1.0"} {
+		if err := os.WriteFile(filepath.Join(cfg.DownloadDir, cfg.GenerateFileName(ver)), []byte("mock content "+ver), 0755); err != nil {
+			t.Fatalf("Failed to create version file: %v", err)
+		}
+	}
+
+	up := updater.NewUpdater("http://example.com", cfg.DownloadDir, cfg)
+	if err := up.SwitchToVersion("1.0.0"); err != nil {
+		t.Fatalf("Failed to switch: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := executeVersions(&buf, up, versionsOptions{JSON: true}); err != nil {
+		t.Fatalf("Failed to list versions: %v", err)
+	}
+
+	var report versionsReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	expected := []updater.VersionStatus{
+		{Version: "1.0.0", Local: true, Active: true},
+		{Version: "1.1.0", Local: true},
+	}
+	if len(report.Versions) != len(expected) {
+		t.Fatalf("Expected %d versions, got %+v", len(expected), report.Versions)
+	}
+	for i, status := range expected {
+		if report.Versions[i] != status {
+			t.Errorf("Expected %+v, got %+v", status, report.Versions[i])
+		}
+	}
+}
+
+func TestVersionsRemoteUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg, up, _ := newTestSetup(t, server.URL+"/download/stable/linux-x64", "1.0.0")
+	cfg.VersionsURL = server.URL + "/versions.json"
+
+	var buf bytes.Buffer
+	if err := executeVersions(&buf, up, versionsOptions{Remote: true}); err != nil {
+		t.Fatalf("Expected versions to degrade gracefully, got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a header and one version, got:\n%s", buf.String())
+	}
+
+	fields := strings.Fields(lines[1])
+	if len(fields) != 3 || fields[0] != "1.0.0" || fields[1] != "?" || fields[2] != "yes" {
+		t.Errorf("Expected 1.0.0 with unknown remote state and local copy, got %q", lines[1])
+	}
+}
+
+func TestUpdateBlockedByVerifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		quarantine bool
+	}{
+		{name: "removed without quarantine dir"},
+		{name: "quarantined", quarantine: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, "1.1.0", "mock content 1.1.0")
+			cfg, up, led := newTestSetup(t, server.URL+"/download/stable/linux-x64")
+			cfg.Verifiers = []string{"command"}
+			cfg.VerifyCommand = []string{"sh", "-c", `echo "rejected by policy server"; exit 1`}
+			cfg.SharedCacheDir = filepath.Join(t.TempDir(), "shared")
+			cfg.SharedCachePopulate = true
+			if tt.quarantine {
+				cfg.QuarantineDir = filepath.Join(t.TempDir(), "quarantine")
+			}
+
+			if err := configureVerifiers(up, cfg); err != nil {
+				t.Fatalf("Failed to configure verifiers: %v", err)
+			}
+
+			err := executeUpdate(up, led, cfg, updateOptions{})
+			if err == nil || !strings.Contains(err.Error(), "rejected by policy server") {
+				t.Fatalf("Expected the verifier to block the update with its reason, got: %v", err)
+			}
+
+			if _, err := os.Lstat(cfg.LatestSymlink); !os.IsNotExist(err) {
+				t.Errorf("Expected no symlink after a rejected update, lstat returned: %v", err)
+			}
+
+			// The rejected file must not remain installable with switch
+			if _, err := os.Stat(filepath.Join(cfg.DownloadDir, cfg.GenerateFileName("1.1.0"))); !os.IsNotExist(err) {
+				t.Errorf("Expected the rejected file to leave the download dir, stat returned: %v", err)
+			}
+
+			// A rejected download must never be offered to other users
+			shared, err := os.ReadDir(cfg.SharedCacheDir)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatalf("Failed to read shared cache: %v", err)
+			}
+			if len(shared) != 0 {
+				t.Errorf("Expected an empty shared cache after a rejected update, got %d entries", len(shared))
+			}
+
+			entries, err := led.ReadAll()
+			if err != nil {
+				t.Fatalf("Failed to read ledger: %v", err)
+			}
+
+			if !tt.quarantine {
+				if len(entries) != 0 {
+					t.Errorf("Expected no ledger entries for a rejected update, got %v", entries)
+				}
+				return
+			}
+
+			if len(entries) != 1 || entries[0].Action != "quarantine" || entries[0].Version != "1.1.0" {
+				t.Fatalf("Expected a single quarantine entry for 1.1.0, got %v", entries)
+			}
+
+			if _, err := os.Stat(filepath.Join(cfg.QuarantineDir, entries[0].Filename)); err != nil {
+				t.Errorf("Expected the rejected file in quarantine: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigureVerifiersRejectsBadConfig(t *testing.T) {
+	cfg, up, _ := newTestSetup(t, "http://example.com")
+	cfg.Verifiers = []string{"missing"}
+
+	if err := configureVerifiers(up, cfg); err == nil || !strings.Contains(err.Error(), "unknown verifier") {
+		t.Errorf("Expected an unknown verifier error, got: %v", err)
+	}
+}
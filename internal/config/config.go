@@ -4,17 +4,72 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Supported hash algorithms for computing and verifying downloaded files
+const (
+	HashSHA256 = "sha256"
+	HashSHA512 = "sha512"
+	HashBLAKE3 = "blake3"
+)
+
+// ProgressBarConfig holds the characters used to draw the progress bar
+type ProgressBarConfig struct {
+	Fill  string `yaml:"fill"`
+	Empty string `yaml:"empty"`
+	Head  string `yaml:"head"`
+}
+
 // Config represents the configuration for the updateCursor tool
 type Config struct {
 	DownloadDir     string `yaml:"download_dir"`
 	FileNamePattern string `yaml:"file_name_pattern"`
 	LatestSymlink   string `yaml:"latest_symlink"`
 	LedgerPath      string `yaml:"ledger_path"`
+	HashAlgorithm   string `yaml:"hash_algorithm"`
+	DownloadURL     string `yaml:"download_url"`
+	QuarantineDir   string `yaml:"quarantine_dir,omitempty"`
+
+	// VersionsURL serves a JSON array of all remotely available versions.
+	// Without it only the latest version is known remotely.
+	VersionsURL string `yaml:"versions_url,omitempty"`
+
+	// SharedCacheDir is a machine-wide cache checked before downloading.
+	// With SharedCachePopulate new downloads are also added to it.
+	SharedCacheDir      string `yaml:"shared_cache_dir,omitempty"`
+	SharedCachePopulate bool   `yaml:"shared_cache_populate,omitempty"`
+
+	// Verifiers are run in order on each download before it is installed.
+	// VerifyCommand is the program run by the "command" verifier.
+	Verifiers     []string `yaml:"verifiers,omitempty"`
+	VerifyCommand []string `yaml:"verify_command,omitempty"`
+
+	// UpToDateExitCode is the exit status of update when nothing was downloaded
+	UpToDateExitCode int `yaml:"uptodate_exit_code"`
+
+	// CheckInterval reuses the last remote version check for this long,
+	// 0 always checks the remote
+	CheckInterval time.Duration `yaml:"check_interval"`
+
+	// HeartbeatInterval is how often a progress line is printed when output
+	// is not a terminal, 0 disables the heartbeat
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// WriteStallTimeout aborts a download when a single disk write blocks
+	// for this long, 0 disables the watchdog
+	WriteStallTimeout time.Duration `yaml:"write_stall_timeout"`
+
+	ProgressBar ProgressBarConfig `yaml:"progress_bar"`
+
+	// RecordUser records the OS user performing each action in the ledger
+	RecordUser bool `yaml:"record_user"`
 }
 
 // NewConfig creates a new config with default values
@@ -24,6 +79,16 @@ func NewConfig() *Config {
 		FileNamePattern: "Cursor-<version>-x86_64.AppImage",
 		LatestSymlink:   "~/Downloads/Cursor/Cursor.AppImage",
 		LedgerPath:      "~/.config/updateCursor/cursor-versions.log",
+		HashAlgorithm:   HashSHA256,
+		DownloadURL:     "https://www.cursor.com/download/stable/linux-x64",
+
+		HeartbeatInterval: 30 * time.Second,
+
+		ProgressBar: ProgressBarConfig{
+			Fill:  "=",
+			Empty: " ",
+			Head:  ">",
+		},
 	}
 }
 
@@ -79,9 +144,73 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("ledger_path cannot be empty")
 	}
 
+	// Each character must take a single cell so the bar keeps its width
+	for name, value := range map[string]string{"fill": c.ProgressBar.Fill, "empty": c.ProgressBar.Empty, "head": c.ProgressBar.Head} {
+		if utf8.RuneCountInString(value) > 1 {
+			return fmt.Errorf("progress_bar.%s must be a single character: %q", name, value)
+		}
+		if r, _ := utf8.DecodeRuneInString(value); value != "" && !isSingleCell(r) {
+			return fmt.Errorf("progress_bar.%s must be a single-width character: %q", name, value)
+		}
+	}
+
+	if c.CheckInterval < 0 {
+		return fmt.Errorf("check_interval cannot be negative: %s", c.CheckInterval)
+	}
+
+	if c.HeartbeatInterval < 0 {
+		return fmt.Errorf("heartbeat_interval cannot be negative: %s", c.HeartbeatInterval)
+	}
+
+	if c.WriteStallTimeout < 0 {
+		return fmt.Errorf("write_stall_timeout cannot be negative: %s", c.WriteStallTimeout)
+	}
+
+	if c.UpToDateExitCode < 0 || c.UpToDateExitCode > 255 {
+		return fmt.Errorf("uptodate_exit_code must be between 0 and 255: %d", c.UpToDateExitCode)
+	}
+
+	switch c.HashAlgorithm {
+	case "", HashSHA256, HashSHA512, HashBLAKE3:
+	default:
+		return fmt.Errorf("hash_algorithm must be one of %s, %s, %s: %s", HashSHA256, HashSHA512, HashBLAKE3, c.HashAlgorithm)
+	}
+
 	return nil
 }
 
+// wideRanges are the common East Asian wide and emoji ranges that take two
+// terminal cells
+var wideRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x2e80, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe4f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// isSingleCell reports whether r is printed in exactly one terminal cell
+func isSingleCell(r rune) bool {
+	if r == utf8.RuneError || !unicode.IsPrint(r) {
+		return false
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me) {
+		return false
+	}
+	return !unicode.Is(wideRanges, r)
+}
+
 // ExpandPaths expands all paths that contain ~ to absolute paths
 func (c *Config) ExpandPaths() error {
 	var err error
@@ -101,6 +230,33 @@ func (c *Config) ExpandPaths() error {
 		return fmt.Errorf("failed to expand ledger_path: %v", err)
 	}
 
+	if c.QuarantineDir != "" {
+		c.QuarantineDir, err = expandHomeDir(c.QuarantineDir)
+		if err != nil {
+			return fmt.Errorf("failed to expand quarantine_dir: %v", err)
+		}
+	}
+
+	if c.SharedCacheDir != "" {
+		c.SharedCacheDir, err = expandHomeDir(c.SharedCacheDir)
+		if err != nil {
+			return fmt.Errorf("failed to expand shared_cache_dir: %v", err)
+		}
+	}
+
+	// Resolve symlinks so relative symlink targets are computed between real
+	// locations. The latest symlink itself must not be resolved, only its directory.
+	c.DownloadDir, err = resolveSymlinks(c.DownloadDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve download_dir: %v", err)
+	}
+
+	symlinkDir, err := resolveSymlinks(filepath.Dir(c.LatestSymlink))
+	if err != nil {
+		return fmt.Errorf("failed to resolve latest_symlink directory: %v", err)
+	}
+	c.LatestSymlink = filepath.Join(symlinkDir, filepath.Base(c.LatestSymlink))
+
 	return nil
 }
 
@@ -109,6 +265,23 @@ func (c *Config) GenerateFileName(version string) string {
 	return strings.ReplaceAll(c.FileNamePattern, "<version>", version)
 }
 
+// VersionFromFileName extracts the version from a filename generated by the
+// pattern, returning "" if the filename doesn't match the pattern
+func (c *Config) VersionFromFileName(filename string) string {
+	parts := strings.SplitN(c.FileNamePattern, "<version>", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(parts[0]) + `([0-9]+(?:\.[0-9]+)*)` + regexp.QuoteMeta(parts[1]) + "$")
+	matches := re.FindStringSubmatch(filename)
+	if len(matches) < 2 {
+		return ""
+	}
+
+	return matches[1]
+}
+
 // FindConfigFile finds the config file in the default location
 func (c *Config) FindConfigFile() (string, error) {
 	homeDir, err := os.UserHomeDir()
@@ -166,3 +339,16 @@ func expandHomeDir(path string) (string, error) {
 	}
 	return path, nil
 }
+
+// resolveSymlinks returns path with all symlinks evaluated. Paths that don't
+// exist yet are returned unchanged since they will be created as real directories.
+func resolveSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return path, nil
+		}
+		return "", err
+	}
+	return resolved, nil
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -31,6 +32,31 @@ func TestDefaultConfig(t *testing.T) {
 	if config.LedgerPath != expectedLedgerPath {
 		t.Errorf("Expected default ledger path %s, got %s", expectedLedgerPath, config.LedgerPath)
 	}
+
+	if config.HashAlgorithm != HashSHA256 {
+		t.Errorf("Expected default hash algorithm %s, got %s", HashSHA256, config.HashAlgorithm)
+	}
+
+	if config.UpToDateExitCode != 0 {
+		t.Errorf("Expected default up-to-date exit code 0, got %d", config.UpToDateExitCode)
+	}
+
+	if config.HeartbeatInterval != 30*time.Second {
+		t.Errorf("Expected default heartbeat interval 30s, got %s", config.HeartbeatInterval)
+	}
+
+	expectedDownloadURL := "https://www.cursor.com/download/stable/linux-x64"
+	if config.DownloadURL != expectedDownloadURL {
+		t.Errorf("Expected default download URL %s, got %s", expectedDownloadURL, config.DownloadURL)
+	}
+
+	if config.CheckInterval != 0 {
+		t.Errorf("Expected check interval to be disabled by default, got %s", config.CheckInterval)
+	}
+
+	if config.WriteStallTimeout != 0 {
+		t.Errorf("Expected write stall watchdog to be disabled by default, got %s", config.WriteStallTimeout)
+	}
 }
 
 func TestLoadConfigFromFile(t *testing.T) {
@@ -157,6 +183,30 @@ func TestGenerateFileName(t *testing.T) {
 	}
 }
 
+func TestVersionFromFileName(t *testing.T) {
+	config := NewConfig()
+
+	tests := []struct {
+		pattern  string
+		filename string
+		expected string
+	}{
+		{pattern: "Cursor-<version>-x86_64.AppImage", filename: "Cursor-1.4.5-x86_64.AppImage", expected: "1.4.5"},
+		{pattern: "Cursor_<version>.AppImage", filename: "Cursor_1.4.5.AppImage", expected: "1.4.5"},
+		{pattern: "Cursor_<version>.AppImage", filename: "Cursor.AppImage", expected: ""},
+		{pattern: "Cursor_<version>.AppImage", filename: "Cursor_1.4.5.AppImage.part", expected: ""},
+		{pattern: "cursor-<version>-linux.AppImage", filename: "Cursor-1.4.5-x86_64.AppImage", expected: ""},
+	}
+
+	for _, tt := range tests {
+		config.FileNamePattern = tt.pattern
+		result := config.VersionFromFileName(tt.filename)
+		if result != tt.expected {
+			t.Errorf("VersionFromFileName(%q) with pattern %q = %q, want %q", tt.filename, tt.pattern, result, tt.expected)
+		}
+	}
+}
+
 func TestConfigFileDiscovery(t *testing.T) {
 	// Test finding config file in default location
 	config := NewConfig()
@@ -258,3 +308,217 @@ func TestConfigWithCustomPaths(t *testing.T) {
 		t.Errorf("Expected filename %s, got %s", expected, filename)
 	}
 }
+
+func TestExpandPathsResolvesSymlinkedDownloadDir(t *testing.T) {
+	// Test that a download dir which is itself a symlink is resolved
+	tempDir := t.TempDir()
+	realDir := filepath.Join(tempDir, "real")
+	linkDir := filepath.Join(tempDir, "link")
+
+	if err := os.MkdirAll(realDir, 0755); err != nil {
+		t.Fatalf("Failed to create real dir: %v", err)
+	}
+	if err := os.Symlink(realDir, linkDir); err != nil {
+		t.Fatalf("Failed to create dir symlink: %v", err)
+	}
+
+	config := NewConfig()
+	config.DownloadDir = linkDir
+	config.LatestSymlink = filepath.Join(linkDir, "Cursor.AppImage")
+	config.LedgerPath = filepath.Join(tempDir, "cursor-versions.log")
+
+	err := config.ExpandPaths()
+	if err != nil {
+		t.Fatalf("Failed to expand paths: %v", err)
+	}
+
+	expectedDir, err := filepath.EvalSymlinks(realDir)
+	if err != nil {
+		t.Fatalf("Failed to resolve real dir: %v", err)
+	}
+
+	if config.DownloadDir != expectedDir {
+		t.Errorf("Expected download dir %s, got %s", expectedDir, config.DownloadDir)
+	}
+
+	// The symlink directory is resolved but the symlink name is kept
+	expectedSymlink := filepath.Join(expectedDir, "Cursor.AppImage")
+	if config.LatestSymlink != expectedSymlink {
+		t.Errorf("Expected latest symlink %s, got %s", expectedSymlink, config.LatestSymlink)
+	}
+}
+
+func TestExpandPathsKeepsMissingDownloadDir(t *testing.T) {
+	// Test that a download dir that doesn't exist yet is left unchanged
+	tempDir := t.TempDir()
+	missingDir := filepath.Join(tempDir, "missing", "cursor")
+
+	config := NewConfig()
+	config.DownloadDir = missingDir
+	config.LatestSymlink = filepath.Join(missingDir, "Cursor.AppImage")
+
+	err := config.ExpandPaths()
+	if err != nil {
+		t.Fatalf("Failed to expand paths: %v", err)
+	}
+
+	if config.DownloadDir != missingDir {
+		t.Errorf("Expected download dir %s, got %s", missingDir, config.DownloadDir)
+	}
+}
+
+func TestConfigHashAlgorithmValidation(t *testing.T) {
+	config := NewConfig()
+
+	for _, algorithm := range []string{HashSHA256, HashSHA512, HashBLAKE3, ""} {
+		config.HashAlgorithm = algorithm
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected hash algorithm %q to be valid, got error: %v", algorithm, err)
+		}
+	}
+
+	config.HashAlgorithm = "md5"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unsupported hash algorithm, but got none")
+	}
+}
+
+func TestLoadConfigKeepsDefaultHashAlgorithm(t *testing.T) {
+	// Test that config files written before hash_algorithm existed keep sha256
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `
+download_dir: "~/Applications/Cursor"
+file_name_pattern: "Cursor_<version>.AppImage"
+latest_symlink: "~/.local/bin/Cursor.AppImage"
+ledger_path: "~/.config/updateCursor/cursor-versions.log"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	config := NewConfig()
+	if err := config.LoadFromFile(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.HashAlgorithm != HashSHA256 {
+		t.Errorf("Expected hash algorithm %s, got %s", HashSHA256, config.HashAlgorithm)
+	}
+}
+
+func TestConfigUpToDateExitCodeValidation(t *testing.T) {
+	config := NewConfig()
+
+	config.UpToDateExitCode = 3
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected exit code 3 to be valid, got error: %v", err)
+	}
+
+	config.UpToDateExitCode = 256
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for exit code out of range, but got none")
+	}
+}
+
+func TestConfigWriteStallTimeoutValidation(t *testing.T) {
+	config := NewConfig()
+
+	config.WriteStallTimeout = 2 * time.Minute
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected write stall timeout 2m to be valid, got error: %v", err)
+	}
+
+	config.WriteStallTimeout = -time.Second
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative write stall timeout, but got none")
+	}
+}
+
+func TestLoadConfigHeartbeatInterval(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	if err := os.WriteFile(configPath, []byte("heartbeat_interval: 45s\n"), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	config := NewConfig()
+	if err := config.LoadFromFile(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.HeartbeatInterval != 45*time.Second {
+		t.Errorf("Expected heartbeat interval 45s, got %s", config.HeartbeatInterval)
+	}
+
+	// Saved configs keep the human readable duration
+	savedPath := filepath.Join(tempDir, "saved.yaml")
+	if err := config.SaveToFile(savedPath); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	content, err := os.ReadFile(savedPath)
+	if err != nil {
+		t.Fatalf("Failed to read saved config: %v", err)
+	}
+
+	if !strings.Contains(string(content), "heartbeat_interval: 45s") {
+		t.Errorf("Expected saved config to contain heartbeat_interval: 45s, got:\n%s", string(content))
+	}
+}
+
+func TestLoadConfigProgressBar(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	configContent := `
+progress_bar:
+  fill: "█"
+  empty: "░"
+`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	config := NewConfig()
+	if err := config.LoadFromFile(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.ProgressBar.Fill != "█" || config.ProgressBar.Empty != "░" {
+		t.Errorf("Expected unicode fill and empty characters, got %+v", config.ProgressBar)
+	}
+
+	// Characters that aren't set keep their default
+	if config.ProgressBar.Head != ">" {
+		t.Errorf("Expected default head character >, got %q", config.ProgressBar.Head)
+	}
+
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid progress bar config, got error: %v", err)
+	}
+
+	config.ProgressBar.Fill = "##"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for multi-character progress bar fill, but got none")
+	}
+
+	// Characters that don't take exactly one cell would also change the width
+	for _, fill := range []string{"██", "漢", "🚀", "\u0301", "\t"} {
+		config.ProgressBar.Fill = fill
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for progress bar fill %q, but got none", fill)
+		}
+	}
+
+	for _, fill := range []string{"#", "█", "▓", "●"} {
+		config.ProgressBar.Fill = fill
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected progress bar fill %q to be valid, got error: %v", fill, err)
+		}
+	}
+}
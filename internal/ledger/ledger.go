@@ -2,6 +2,7 @@ package ledger
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,19 +10,98 @@ import (
 	"time"
 )
 
-// Entry represents a single entry in the update ledger
+// DefaultHashAlgorithm is assumed for entries that don't record an algorithm
+const DefaultHashAlgorithm = "sha256"
+
+// Entry represents a single entry in the update ledger. Its JSON form is
+// described by entryJSON.
 type Entry struct {
 	Timestamp  time.Time
 	Version    string
 	InternalID string
 	Filename   string
-	SHA256     string
+	SHA256     string // file hash, computed with HashAlgorithm
 	Action     string
+
+	// HashAlgorithm names the algorithm the SHA256 field was computed with.
+	// It is stored in an optional trailing column, empty means
+	// DefaultHashAlgorithm.
+	HashAlgorithm string
+
+	// User is the OS user who performed the action, stored in an optional
+	// trailing column when user recording is enabled
+	User string
+}
+
+// Algorithm returns the hash algorithm of the entry, defaulting to sha256
+func (e Entry) Algorithm() string {
+	if e.HashAlgorithm == "" {
+		return DefaultHashAlgorithm
+	}
+	return e.HashAlgorithm
+}
+
+// entryJSON is the JSON form of an Entry. The hash is always in the neutral
+// "hash" key along with its algorithm; "sha256" is only kept for sha256
+// hashes so existing consumers never read another algorithm's hash from it.
+type entryJSON struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Version       string    `json:"version"`
+	InternalID    string    `json:"internal_id"`
+	Filename      string    `json:"filename"`
+	Hash          string    `json:"hash"`
+	HashAlgorithm string    `json:"hash_algorithm"`
+	SHA256        string    `json:"sha256,omitempty"`
+	Action        string    `json:"action"`
+	User          string    `json:"user,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (e Entry) MarshalJSON() ([]byte, error) {
+	out := entryJSON{
+		Timestamp:     e.Timestamp,
+		Version:       e.Version,
+		InternalID:    e.InternalID,
+		Filename:      e.Filename,
+		Hash:          e.SHA256,
+		HashAlgorithm: e.Algorithm(),
+		Action:        e.Action,
+		User:          e.User,
+	}
+	if e.Algorithm() == DefaultHashAlgorithm {
+		out.SHA256 = e.SHA256
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting the legacy "sha256"
+// key when "hash" is missing
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var in entryJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*e = Entry{
+		Timestamp:     in.Timestamp,
+		Version:       in.Version,
+		InternalID:    in.InternalID,
+		Filename:      in.Filename,
+		SHA256:        in.Hash,
+		Action:        in.Action,
+		HashAlgorithm: in.HashAlgorithm,
+		User:          in.User,
+	}
+	if e.SHA256 == "" {
+		e.SHA256 = in.SHA256
+	}
+	return nil
 }
 
 // Ledger manages the update history file
 type Ledger struct {
 	filepath string
+	user     string
 }
 
 // NewLedger creates a new ledger instance
@@ -31,8 +111,17 @@ func NewLedger(filepath string) *Ledger {
 	}
 }
 
+// SetUser sets the user recorded on appended entries that don't name one
+func (l *Ledger) SetUser(user string) {
+	l.user = user
+}
+
 // Append adds a new entry to the ledger
 func (l *Ledger) Append(entry Entry) error {
+	if entry.User == "" {
+		entry.User = l.user
+	}
+
 	// Ensure directory exists
 	dir := filepath.Dir(l.filepath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -46,18 +135,8 @@ func (l *Ledger) Append(entry Entry) error {
 	}
 	defer file.Close()
 
-	// Format entry as TSV line
-	line := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\n",
-		entry.Timestamp.UTC().Format(time.RFC3339),
-		entry.Version,
-		entry.InternalID,
-		entry.Filename,
-		entry.SHA256,
-		entry.Action,
-	)
-
 	// Write line to file
-	if _, err := file.WriteString(line); err != nil {
+	if _, err := file.WriteString(formatEntry(entry)); err != nil {
 		return fmt.Errorf("failed to write to ledger file: %v", err)
 	}
 
@@ -66,19 +145,33 @@ func (l *Ledger) Append(entry Entry) error {
 
 // ReadAll reads all entries from the ledger
 func (l *Ledger) ReadAll() ([]Entry, error) {
+	entries := []Entry{}
+	err := l.Each(func(entry Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Each streams entries from the ledger in file order without buffering them,
+// stopping at the first error returned by fn
+func (l *Ledger) Each(fn func(Entry) error) error {
 	// Check if file exists
 	if _, err := os.Stat(l.filepath); os.IsNotExist(err) {
-		return []Entry{}, nil
+		return nil
 	}
 
 	// Open file for reading
 	file, err := os.Open(l.filepath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open ledger file: %v", err)
+		return fmt.Errorf("failed to open ledger file: %v", err)
 	}
 	defer file.Close()
 
-	var entries []Entry
 	scanner := bufio.NewScanner(file)
 
 	for scanner.Scan() {
@@ -93,14 +186,33 @@ func (l *Ledger) ReadAll() ([]Entry, error) {
 			continue
 		}
 
-		entries = append(entries, entry)
+		if err := fn(entry); err != nil {
+			return err
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading ledger file: %v", err)
+		return fmt.Errorf("error reading ledger file: %v", err)
 	}
 
-	return entries, nil
+	return nil
+}
+
+// FindByFilename finds all entries for the given filename
+func (l *Ledger) FindByFilename(filename string) ([]Entry, error) {
+	entries, err := l.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Entry
+	for _, entry := range entries {
+		if entry.Filename == filename {
+			found = append(found, entry)
+		}
+	}
+
+	return found, nil
 }
 
 // FindByInternalID finds all entries with the given internal ID
@@ -142,11 +254,40 @@ func (l *Ledger) GetLatestVersion() (Entry, error) {
 	return latest, nil
 }
 
+// formatEntry formats an Entry struct as a TSV line. Optional trailing
+// columns are only written when set so older ledgers stay readable.
+func formatEntry(entry Entry) string {
+	parts := []string{
+		entry.Timestamp.UTC().Format(time.RFC3339),
+		entry.Version,
+		entry.InternalID,
+		entry.Filename,
+		entry.SHA256,
+		entry.Action,
+	}
+
+	// sha256 is left implicit so default ledgers stay readable by older
+	// versions, which skip lines with extra columns
+	algorithm := entry.HashAlgorithm
+	if algorithm == DefaultHashAlgorithm {
+		algorithm = ""
+	}
+
+	// Drop trailing empty optional columns
+	optional := []string{algorithm, entry.User}
+	for len(optional) > 0 && optional[len(optional)-1] == "" {
+		optional = optional[:len(optional)-1]
+	}
+	parts = append(parts, optional...)
+
+	return strings.Join(parts, "\t") + "\n"
+}
+
 // parseEntry parses a TSV line into an Entry struct
 func parseEntry(line string) (Entry, error) {
 	parts := strings.Split(line, "\t")
-	if len(parts) != 6 {
-		return Entry{}, fmt.Errorf("invalid entry format: expected 6 parts, got %d", len(parts))
+	if len(parts) < 6 || len(parts) > 8 {
+		return Entry{}, fmt.Errorf("invalid entry format: expected 6 to 8 parts, got %d", len(parts))
 	}
 
 	// Parse timestamp
@@ -155,12 +296,22 @@ func parseEntry(line string) (Entry, error) {
 		return Entry{}, fmt.Errorf("invalid timestamp format: %v", err)
 	}
 
-	return Entry{
+	entry := Entry{
 		Timestamp:  timestamp,
 		Version:    parts[1],
 		InternalID: parts[2],
 		Filename:   parts[3],
 		SHA256:     parts[4],
 		Action:     parts[5],
-	}, nil
+	}
+
+	if len(parts) > 6 {
+		entry.HashAlgorithm = parts[6]
+	}
+
+	if len(parts) > 7 {
+		entry.User = parts[7]
+	}
+
+	return entry, nil
 }
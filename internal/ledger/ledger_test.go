@@ -1,8 +1,11 @@
 package ledger
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -220,3 +223,270 @@ func TestLedgerFileFormat(t *testing.T) {
 		t.Errorf("Expected TSV format:\n%q\ngot:\n%q", expectedLine, string(content))
 	}
 }
+
+func TestLedgerHashAlgorithmRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	ledgerPath := filepath.Join(tempDir, "test-ledger.log")
+
+	ledger := NewLedger(ledgerPath)
+
+	for _, algorithm := range []string{"sha256", "sha512", "blake3"} {
+		entry := Entry{
+			Timestamp:     time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			Version:       "1.0.0",
+			Filename:      "Cursor-1.0.0-x86_64.AppImage",
+			SHA256:        "hash-" + algorithm,
+			Action:        "update",
+			HashAlgorithm: algorithm,
+		}
+		if err := ledger.Append(entry); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	entries, err := ledger.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read entries: %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	for i, algorithm := range []string{"sha256", "sha512", "blake3"} {
+		if entries[i].Algorithm() != algorithm {
+			t.Errorf("Expected algorithm %s, got %s", algorithm, entries[i].Algorithm())
+		}
+		if entries[i].SHA256 != "hash-"+algorithm {
+			t.Errorf("Expected hash hash-%s, got %s", algorithm, entries[i].SHA256)
+		}
+	}
+
+	// Only non-default algorithms widen the line, so older versions can
+	// still read sha256 entries
+	content, err := os.ReadFile(ledgerPath)
+	if err != nil {
+		t.Fatalf("Failed to read ledger file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	for i, columns := range []int{6, 7, 7} {
+		if got := len(strings.Split(lines[i], "\t")); got != columns {
+			t.Errorf("Expected %d columns in line %d, got %d: %q", columns, i+1, got, lines[i])
+		}
+	}
+}
+
+func TestLedgerReadsLegacyEntriesAsSHA256(t *testing.T) {
+	tempDir := t.TempDir()
+	ledgerPath := filepath.Join(tempDir, "test-ledger.log")
+
+	// Entries written before the algorithm column existed
+	legacy := "2024-01-01T12:00:00Z\t1.0.0\t12345\tCursor-1.0.0-x86_64.AppImage\tabc123def456\tdownload\n"
+	if err := os.WriteFile(ledgerPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write legacy ledger: %v", err)
+	}
+
+	ledger := NewLedger(ledgerPath)
+
+	entries, err := ledger.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read entries: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	if entries[0].HashAlgorithm != "" {
+		t.Errorf("Expected no recorded algorithm, got %s", entries[0].HashAlgorithm)
+	}
+
+	if entries[0].Algorithm() != DefaultHashAlgorithm {
+		t.Errorf("Expected algorithm %s, got %s", DefaultHashAlgorithm, entries[0].Algorithm())
+	}
+}
+
+func TestLedgerFindByFilename(t *testing.T) {
+	tempDir := t.TempDir()
+	ledgerPath := filepath.Join(tempDir, "test-ledger.log")
+
+	ledger := NewLedger(ledgerPath)
+
+	for _, ver := range []string{"1.0.0", "1.1.0", "1.0.0"} {
+		entry := Entry{
+			Timestamp: time.Now(),
+			Version:   ver,
+			Filename:  "Cursor-" + ver + "-x86_64.AppImage",
+			Action:    "update",
+		}
+		if err := ledger.Append(entry); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	found, err := ledger.FindByFilename("Cursor-1.0.0-x86_64.AppImage")
+	if err != nil {
+		t.Fatalf("Failed to find by filename: %v", err)
+	}
+
+	if len(found) != 2 {
+		t.Errorf("Expected 2 entries for Cursor-1.0.0-x86_64.AppImage, got %d", len(found))
+	}
+}
+
+func TestLedgerEachStreamsEntries(t *testing.T) {
+	tempDir := t.TempDir()
+	ledgerPath := filepath.Join(tempDir, "test-ledger.log")
+
+	ledger := NewLedger(ledgerPath)
+
+	// A missing ledger streams nothing
+	err := ledger.Each(func(entry Entry) error {
+		t.Errorf("Expected no entries, got %+v", entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream missing ledger: %v", err)
+	}
+
+	for _, ver := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		entry := Entry{
+			Timestamp: time.Now(),
+			Version:   ver,
+			Filename:  "Cursor-" + ver + "-x86_64.AppImage",
+			Action:    "update",
+		}
+		if err := ledger.Append(entry); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	// Entries arrive in file order and an error stops the stream
+	stop := fmt.Errorf("stop")
+	var versions []string
+	err = ledger.Each(func(entry Entry) error {
+		versions = append(versions, entry.Version)
+		if entry.Version == "1.1.0" {
+			return stop
+		}
+		return nil
+	})
+
+	if err != stop {
+		t.Errorf("Expected stream to return the callback error, got %v", err)
+	}
+
+	if len(versions) != 2 || versions[0] != "1.0.0" || versions[1] != "1.1.0" {
+		t.Errorf("Expected versions [1.0.0 1.1.0], got %v", versions)
+	}
+}
+
+func TestLedgerRecordsUser(t *testing.T) {
+	tempDir := t.TempDir()
+	ledgerPath := filepath.Join(tempDir, "test-ledger.log")
+
+	ledger := NewLedger(ledgerPath)
+
+	entry := Entry{
+		Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Version:   "1.0.0",
+		Filename:  "Cursor-1.0.0-x86_64.AppImage",
+		Action:    "switch",
+	}
+
+	// Without a user no extra columns are written
+	if err := ledger.Append(entry); err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+
+	// With a user the algorithm column is kept empty to keep positions
+	ledger.SetUser("alice")
+	if err := ledger.Append(entry); err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+
+	content, err := os.ReadFile(ledgerPath)
+	if err != nil {
+		t.Fatalf("Failed to read ledger file: %v", err)
+	}
+
+	expected := "2024-01-01T12:00:00Z\t1.0.0\t\tCursor-1.0.0-x86_64.AppImage\t\tswitch\n" +
+		"2024-01-01T12:00:00Z\t1.0.0\t\tCursor-1.0.0-x86_64.AppImage\t\tswitch\t\talice\n"
+	if string(content) != expected {
+		t.Errorf("Expected TSV format:\n%q\ngot:\n%q", expected, string(content))
+	}
+
+	entries, err := ledger.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read entries: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	if entries[0].User != "" {
+		t.Errorf("Expected no user on first entry, got %s", entries[0].User)
+	}
+
+	if entries[1].User != "alice" || entries[1].Algorithm() != DefaultHashAlgorithm {
+		t.Errorf("Expected user alice with default algorithm, got %+v", entries[1])
+	}
+}
+
+func TestEntryJSONHashKeys(t *testing.T) {
+	tests := []struct {
+		algorithm    string
+		expectSHA256 bool
+	}{
+		{algorithm: "", expectSHA256: true},
+		{algorithm: "sha256", expectSHA256: true},
+		{algorithm: "sha512"},
+		{algorithm: "blake3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			entry := Entry{Version: "1.0.0", SHA256: "abc123", Action: "update", HashAlgorithm: tt.algorithm}
+
+			data, err := json.Marshal(entry)
+			if err != nil {
+				t.Fatalf("Failed to marshal entry: %v", err)
+			}
+
+			var fields map[string]interface{}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("Failed to unmarshal entry: %v", err)
+			}
+
+			if fields["hash"] != "abc123" || fields["hash_algorithm"] != entry.Algorithm() {
+				t.Errorf("Expected hash abc123 with algorithm %s, got %s", entry.Algorithm(), data)
+			}
+
+			// Only sha256 hashes may appear under the sha256 key
+			if _, ok := fields["sha256"]; ok != tt.expectSHA256 {
+				t.Errorf("Expected sha256 key present: %v, got %s", tt.expectSHA256, data)
+			}
+
+			var decoded Entry
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Failed to decode entry: %v", err)
+			}
+
+			if decoded.SHA256 != "abc123" || decoded.Algorithm() != entry.Algorithm() {
+				t.Errorf("Expected round-tripped hash abc123 (%s), got %+v", entry.Algorithm(), decoded)
+			}
+		})
+	}
+
+	// Output from before the hash key existed still decodes
+	var legacy Entry
+	if err := json.Unmarshal([]byte(`{"version":"1.0.0","sha256":"abc123"}`), &legacy); err != nil {
+		t.Fatalf("Failed to decode legacy entry: %v", err)
+	}
+
+	if legacy.SHA256 != "abc123" || legacy.Algorithm() != DefaultHashAlgorithm {
+		t.Errorf("Expected legacy sha256 hash abc123, got %+v", legacy)
+	}
+}
@@ -0,0 +1,100 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkCache is the on-disk record of the last remote version check
+type checkCache struct {
+	Key           string    `json:"key"`
+	CheckedAt     time.Time `json:"checked_at"`
+	RemoteVersion string    `json:"remote_version"`
+}
+
+// SetCheckCache enables reusing remote version checks stored at path for
+// up to interval. A zero interval disables the cache.
+func (u *Updater) SetCheckCache(path string, interval time.Duration) {
+	u.checkCachePath = path
+	u.checkInterval = interval
+}
+
+// GetRemoteVersionCached returns the remote version from the check cache
+// when it is fresh, and checks the remote otherwise
+func (u *Updater) GetRemoteVersionCached() (string, error) {
+	if version, ok := u.readCheckCache(); ok {
+		return version, nil
+	}
+	return u.GetRemoteVersion()
+}
+
+// checkCacheKey identifies the settings a cached check depends on, so
+// changing them invalidates the cache
+func (u *Updater) checkCacheKey() string {
+	sum := sha256.Sum256([]byte(u.downloadURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// readCheckCache returns the cached remote version if the cache is enabled,
+// was written for the current settings and is younger than the interval
+func (u *Updater) readCheckCache() (string, bool) {
+	if u.checkCachePath == "" || u.checkInterval <= 0 {
+		return "", false
+	}
+
+	data, err := os.ReadFile(u.checkCachePath)
+	if err != nil {
+		return "", false
+	}
+
+	var cache checkCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return "", false
+	}
+
+	if cache.Key != u.checkCacheKey() || cache.RemoteVersion == "" {
+		return "", false
+	}
+
+	// A stamp from the future means the clock moved, so don't trust it
+	age := time.Since(cache.CheckedAt)
+	if age < 0 || age > u.checkInterval {
+		return "", false
+	}
+
+	return cache.RemoteVersion, true
+}
+
+// writeCheckCache records a remote version check when the cache is enabled.
+// Nothing is recorded while the local clock is skewed from the server's,
+// since the stamp's age would be meaningless once the clock is corrected.
+func (u *Updater) writeCheckCache(remoteVersion string) {
+	if u.checkCachePath == "" || u.checkInterval <= 0 {
+		return
+	}
+
+	if u.clockSkewed() {
+		return
+	}
+
+	data, err := json.Marshal(checkCache{
+		Key:           u.checkCacheKey(),
+		CheckedAt:     time.Now().UTC(),
+		RemoteVersion: remoteVersion,
+	})
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(u.checkCachePath), 0755); err == nil {
+		err = os.WriteFile(u.checkCachePath, data, 0644)
+	}
+	if err != nil {
+		u.warn(fmt.Sprintf("failed to write check cache: %v", err))
+	}
+}
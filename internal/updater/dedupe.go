@@ -0,0 +1,107 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// DedupeResult summarizes a dedupe run
+type DedupeResult struct {
+	Linked    []string // duplicates replaced with a hardlink
+	Skipped   []string // duplicates that could not be linked (different device)
+	Reclaimed int64    // bytes freed by linking
+}
+
+// Dedupe finds byte-identical version files in the download directory and
+// replaces duplicates with hardlinks to a single file so every version name
+// keeps working while the content is only stored once
+func (u *Updater) Dedupe() (DedupeResult, error) {
+	var result DedupeResult
+
+	names, err := u.localVersionFiles()
+	if err != nil {
+		return result, err
+	}
+
+	// Group candidates by size first so only possible duplicates are hashed
+	bySize := make(map[int64][]string)
+	infos := make(map[string]os.FileInfo)
+	for _, name := range names {
+		info, err := os.Stat(u.getDownloadPath(name))
+		if err != nil {
+			return result, fmt.Errorf("failed to stat %s: %v", name, err)
+		}
+		infos[name] = info
+		bySize[info.Size()] = append(bySize[info.Size()], name)
+	}
+
+	for _, name := range names {
+		group := bySize[infos[name].Size()]
+		if len(group) < 2 || group[0] != name {
+			continue
+		}
+
+		// Keep the first file with a given hash, link the rest to it
+		canonical := make(map[string]string)
+		for _, candidate := range group {
+			hash, err := u.CalculateHash(u.getDownloadPath(candidate))
+			if err != nil {
+				return result, fmt.Errorf("failed to hash %s: %v", candidate, err)
+			}
+
+			original, ok := canonical[hash]
+			if !ok {
+				canonical[hash] = candidate
+				continue
+			}
+
+			if os.SameFile(infos[original], infos[candidate]) {
+				continue
+			}
+
+			if !sameDevice(infos[original], infos[candidate]) {
+				result.Skipped = append(result.Skipped, candidate)
+				continue
+			}
+
+			if err := u.replaceWithHardlink(original, candidate); err != nil {
+				return result, err
+			}
+
+			result.Linked = append(result.Linked, candidate)
+			result.Reclaimed += infos[candidate].Size()
+		}
+	}
+
+	return result, nil
+}
+
+// replaceWithHardlink atomically replaces duplicate with a hardlink to original
+func (u *Updater) replaceWithHardlink(original, duplicate string) error {
+	originalPath := u.getDownloadPath(original)
+	duplicatePath := u.getDownloadPath(duplicate)
+	tempPath := filepath.Join(filepath.Dir(duplicatePath), "."+duplicate+".dedupe")
+
+	if err := os.Link(originalPath, tempPath); err != nil {
+		return fmt.Errorf("failed to link %s to %s: %v", duplicate, original, err)
+	}
+
+	if err := os.Rename(tempPath, duplicatePath); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to replace %s: %v", duplicate, err)
+	}
+
+	return nil
+}
+
+// sameDevice reports whether two files are on the same device
+func sameDevice(a, b os.FileInfo) bool {
+	statA, okA := a.Sys().(*syscall.Stat_t)
+	statB, okB := b.Sys().(*syscall.Stat_t)
+	if !okA || !okB {
+		return false
+	}
+	return statA.Dev == statB.Dev
+}
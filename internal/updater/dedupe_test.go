@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDedupeLinksIdenticalFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	updater := NewUpdater("http://example.com", tempDir, nil)
+
+	// Two rebuilds with identical content and one different version
+	files := map[string]string{
+		"Cursor-1.0.0-x86_64.AppImage": "identical content",
+		"Cursor-1.0.1-x86_64.AppImage": "identical content",
+		"Cursor-1.1.0-x86_64.AppImage": "different content",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	// The launch symlink must be ignored
+	if err := os.Symlink("Cursor-1.0.0-x86_64.AppImage", filepath.Join(tempDir, "Cursor.AppImage")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	result, err := updater.Dedupe()
+	if err != nil {
+		t.Fatalf("Failed to dedupe: %v", err)
+	}
+
+	if len(result.Linked) != 1 || result.Linked[0] != "Cursor-1.0.1-x86_64.AppImage" {
+		t.Errorf("Expected Cursor-1.0.1-x86_64.AppImage to be linked, got %v", result.Linked)
+	}
+
+	if result.Reclaimed != int64(len("identical content")) {
+		t.Errorf("Expected %d bytes reclaimed, got %d", len("identical content"), result.Reclaimed)
+	}
+
+	stat := func(name string) os.FileInfo {
+		info, err := os.Stat(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", name, err)
+		}
+		return info
+	}
+
+	// Identical files share an inode
+	if !os.SameFile(stat("Cursor-1.0.0-x86_64.AppImage"), stat("Cursor-1.0.1-x86_64.AppImage")) {
+		t.Error("Expected identical files to share an inode after dedupe")
+	}
+
+	// Different content is left alone
+	if os.SameFile(stat("Cursor-1.0.0-x86_64.AppImage"), stat("Cursor-1.1.0-x86_64.AppImage")) {
+		t.Error("Expected different files to keep separate inodes")
+	}
+
+	// All version names still work
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Expected %s to contain %q, got %q", name, content, string(data))
+		}
+	}
+
+	// Running again finds nothing new to link
+	result, err = updater.Dedupe()
+	if err != nil {
+		t.Fatalf("Failed to dedupe again: %v", err)
+	}
+
+	if len(result.Linked) != 0 {
+		t.Errorf("Expected no files linked on second run, got %v", result.Linked)
+	}
+}
+
+func TestDedupeEmptyDownloadDir(t *testing.T) {
+	tempDir := t.TempDir()
+
+	updater := NewUpdater("http://example.com", filepath.Join(tempDir, "missing"), nil)
+
+	result, err := updater.Dedupe()
+	if err != nil {
+		t.Fatalf("Expected no error for missing download dir, got: %v", err)
+	}
+
+	if len(result.Linked) != 0 {
+		t.Errorf("Expected no files linked, got %v", result.Linked)
+	}
+}
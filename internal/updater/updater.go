@@ -2,15 +2,22 @@ package updater
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
+	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"lukechampine.com/blake3"
+
 	"github.com/CoGorm/updateCursor/internal/config"
 	"github.com/CoGorm/updateCursor/internal/version"
 )
@@ -26,6 +33,13 @@ type ProgressUpdate struct {
 // ProgressCallback is a function type for progress updates
 type ProgressCallback func(ProgressUpdate)
 
+// WarningCallback is a function type for non-fatal warnings
+type WarningCallback func(message string)
+
+// clockSkewThreshold is how far the local clock may differ from the server's
+// Date header before a warning is emitted
+const clockSkewThreshold = 5 * time.Minute
+
 // ProgressReader wraps an io.Reader to track download progress
 type ProgressReader struct {
 	Reader          io.Reader
@@ -74,7 +88,14 @@ type Updater struct {
 	workDir          string
 	launchLink       string
 	progressCallback ProgressCallback
+	warningCallback  WarningCallback
 	config           *config.Config
+	clockSkew        time.Duration
+	skewWarned       bool
+	checkCachePath   string
+	checkInterval    time.Duration
+	fs               fileSystem
+	verifiers        []Verifier
 }
 
 // NewUpdater creates a new updater instance
@@ -84,15 +105,37 @@ func NewUpdater(downloadURL, workDir string, cfg *config.Config) *Updater {
 		workDir:     workDir,
 		launchLink:  filepath.Join(workDir, "Cursor.AppImage"),
 		config:      cfg,
+		fs:          osFileSystem{},
 	}
 }
 
+// DownloadOptions controls how Download fetches a version
+type DownloadOptions struct {
+	// Version is the already resolved version to download. The remote is
+	// resolved again when it is empty.
+	Version string
+
+	// SkipSharedCache always downloads from the network, so a bad copy in
+	// the shared cache can be replaced
+	SkipSharedCache bool
+}
+
 // DownloadCursor downloads the latest Cursor version and returns the filename
 func (u *Updater) DownloadCursor() (string, error) {
+	return u.Download(DownloadOptions{})
+}
+
+// Download downloads the latest Cursor version as described by opts and
+// returns the filename
+func (u *Updater) Download(opts DownloadOptions) (string, error) {
 	// Get remote version
-	remoteVersion, err := u.GetRemoteVersion()
-	if err != nil {
-		return "", fmt.Errorf("failed to get remote version: %v", err)
+	remoteVersion := opts.Version
+	if remoteVersion == "" {
+		var err error
+		remoteVersion, err = u.GetRemoteVersion()
+		if err != nil {
+			return "", fmt.Errorf("failed to get remote version: %v", err)
+		}
 	}
 
 	// Construct filename using config pattern
@@ -104,6 +147,15 @@ func (u *Updater) DownloadCursor() (string, error) {
 		return filename, nil
 	}
 
+	// Reuse a copy from the shared cache before going to the network
+	if !opts.SkipSharedCache {
+		if found, err := u.fetchFromSharedCache(filename); err != nil {
+			u.warn(fmt.Sprintf("failed to use shared cache: %v", err))
+		} else if found {
+			return filename, nil
+		}
+	}
+
 	// Download the file
 	resp, err := http.Get(u.downloadURL)
 	if err != nil {
@@ -130,36 +182,68 @@ func (u *Updater) DownloadCursor() (string, error) {
 		return "", fmt.Errorf("download failed with status: %d", resp.StatusCode)
 	}
 
+	// The remote may have moved on since the version was resolved, and the
+	// file must not be saved under another version's name
+	if served := servedVersion(resp); served != "" && served != remoteVersion {
+		return "", fmt.Errorf("remote served version %s instead of %s", served, remoteVersion)
+	}
+
 	// Ensure directories exist before creating the file
 	if err := u.ensureDirectories(); err != nil {
 		return "", fmt.Errorf("failed to ensure directories: %v", err)
 	}
 
-	// Create the file
-	file, err := os.Create(filepath)
+	// Create the file. It is closed explicitly, exactly once, because a
+	// stalled write may still be using it when the watchdog gives up.
+	file, err := u.fs.Create(filepath)
 	if err != nil {
 		return "", fmt.Errorf("failed to create file: %v", err)
 	}
-	defer file.Close()
 
 	// Get total file size for progress tracking
 	totalBytes := resp.ContentLength
 	var bytesDownloaded int64
 
+	// Progress stops being reported once the download is abandoned
+	var abandoned atomic.Bool
+	var callback ProgressCallback
+	if u.progressCallback != nil {
+		callback = func(update ProgressUpdate) {
+			if !abandoned.Load() {
+				u.progressCallback(update)
+			}
+		}
+	}
+
 	// Create a progress reader
 	progressReader := &ProgressReader{
 		Reader:          resp.Body,
 		TotalBytes:      totalBytes,
 		BytesDownloaded: &bytesDownloaded,
-		Callback:        u.progressCallback,
+		Callback:        callback,
 	}
 
-	// Copy content to file with progress tracking
-	_, err = io.Copy(file, progressReader)
+	// Copy content to file with progress tracking, giving up if the disk hangs
+	err = copyWithStallWatchdog(file, progressReader, u.writeStallTimeout(), func() {
+		resp.Body.Close()
+	})
 	if err != nil {
+		// After a stall the copy goroutine is still blocked in Write and only
+		// exits once that write returns, so silence its progress updates and
+		// close the file under it so the write can't complete
+		abandoned.Store(true)
+		_ = file.Close()
+
+		// Don't leave a partial file that would later pass for a complete download
+		os.Remove(filepath)
 		return "", fmt.Errorf("failed to write file: %v", err)
 	}
 
+	if err := file.Close(); err != nil {
+		os.Remove(filepath)
+		return "", fmt.Errorf("failed to close file: %v", err)
+	}
+
 	// Make file executable
 	if err := os.Chmod(filepath, 0755); err != nil {
 		return "", fmt.Errorf("failed to make file executable: %v", err)
@@ -168,7 +252,132 @@ func (u *Updater) DownloadCursor() (string, error) {
 	return filename, nil
 }
 
-// GetRemoteVersion gets the remote version by following the download URL redirect
+// fetchFromSharedCache copies filename from the shared cache into the
+// download directory, reporting whether the cache had a verified copy. Files
+// are never hardlinked, so other users of the cache can't modify the
+// installed copy, and only files matching the SHA256 recorded next to them
+// when the cache was populated are used.
+func (u *Updater) fetchFromSharedCache(filename string) (bool, error) {
+	if u.config == nil || u.config.SharedCacheDir == "" {
+		return false, nil
+	}
+
+	cachedPath := filepath.Join(u.config.SharedCacheDir, filename)
+	info, err := os.Stat(cachedPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return false, nil
+	}
+
+	expected, err := readSharedCacheHash(cachedPath)
+	if err != nil {
+		return false, fmt.Errorf("no recorded hash for %s: %v", filename, err)
+	}
+
+	if err := u.ensureDirectories(); err != nil {
+		return false, fmt.Errorf("failed to ensure directories: %v", err)
+	}
+
+	// Verify the private copy rather than the cached file, which may change
+	// while it is being read
+	downloadPath := u.getDownloadPath(filename)
+	tempPath := filepath.Join(filepath.Dir(downloadPath), "."+filename+".tmp")
+	if err := copyFile(cachedPath, tempPath); err != nil {
+		_ = os.Remove(tempPath)
+		return false, fmt.Errorf("failed to copy from shared cache: %v", err)
+	}
+
+	if err := u.VerifyHash(tempPath, config.HashSHA256, expected); err != nil {
+		_ = os.Remove(tempPath)
+		return false, fmt.Errorf("rejected shared cache copy of %s: %v", filename, err)
+	}
+
+	if err := os.Chmod(tempPath, 0755); err != nil {
+		_ = os.Remove(tempPath)
+		return false, fmt.Errorf("failed to make file executable: %v", err)
+	}
+
+	if err := os.Rename(tempPath, downloadPath); err != nil {
+		_ = os.Remove(tempPath)
+		return false, fmt.Errorf("failed to move shared cache copy into place: %v", err)
+	}
+
+	return true, nil
+}
+
+// PopulateSharedCache copies a downloaded file into the shared cache along
+// with its SHA256 when shared_cache_populate is enabled. Call it only once
+// the file has been verified and installed, so other users never receive a
+// rejected download. An existing cache entry is kept unless replace is set.
+// Both files are staged under temporary names so other users never see a
+// partial copy, and the hash is published last so the file is never used
+// before it can be verified.
+func (u *Updater) PopulateSharedCache(filename string, replace bool) error {
+	if u.config == nil || u.config.SharedCacheDir == "" || !u.config.SharedCachePopulate {
+		return nil
+	}
+
+	cachedPath := filepath.Join(u.config.SharedCacheDir, filename)
+	if _, err := os.Stat(cachedPath); err == nil && !replace {
+		return nil
+	}
+
+	downloadPath := u.getDownloadPath(filename)
+	fileHash, err := u.CalculateHashWith(downloadPath, config.HashSHA256)
+	if err != nil {
+		return err
+	}
+
+	tempPath := filepath.Join(u.config.SharedCacheDir, "."+filename+".tmp")
+	if err := copyFile(downloadPath, tempPath); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	hashPath := sharedCacheHashPath(cachedPath)
+	tempHashPath := filepath.Join(u.config.SharedCacheDir, "."+filepath.Base(hashPath)+".tmp")
+	if err := os.WriteFile(tempHashPath, []byte(fileHash+"  "+filename+"\n"), 0644); err != nil {
+		_ = os.Remove(tempPath)
+		_ = os.Remove(tempHashPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, cachedPath); err != nil {
+		_ = os.Remove(tempPath)
+		_ = os.Remove(tempHashPath)
+		return err
+	}
+
+	if err := os.Rename(tempHashPath, hashPath); err != nil {
+		_ = os.Remove(tempHashPath)
+		return err
+	}
+
+	return nil
+}
+
+// sharedCacheHashPath returns the path of the SHA256 recorded for a cached file
+func sharedCacheHashPath(cachedPath string) string {
+	return cachedPath + ".sha256"
+}
+
+// readSharedCacheHash reads the SHA256 recorded for a cached file, stored in
+// sha256sum format
+func readSharedCacheHash(cachedPath string) (string, error) {
+	data, err := os.ReadFile(sharedCacheHashPath(cachedPath))
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != hex.EncodedLen(sha256.Size) {
+		return "", fmt.Errorf("malformed hash file %s", sharedCacheHashPath(cachedPath))
+	}
+
+	return fields[0], nil
+}
+
+// GetRemoteVersion gets the remote version by following the download URL redirect.
+// Servers that serve the file directly are handled through the Content-Disposition header.
 func (u *Updater) GetRemoteVersion() (string, error) {
 	// Use a client that follows redirects but allows us to capture the final URL
 	client := &http.Client{
@@ -185,16 +394,76 @@ func (u *Updater) GetRemoteVersion() (string, error) {
 	}
 	defer resp.Body.Close()
 
+	u.checkClockSkew(resp.Header.Get("Date"))
+
+	if version := servedVersion(resp); version != "" {
+		u.writeCheckCache(version)
+		return version, nil
+	}
+
 	// The response URL will be the final URL after all redirects
 	finalURL := resp.Request.URL.String()
+	return "", fmt.Errorf("could not extract version from URL %s or Content-Disposition header (status %d)", finalURL, resp.StatusCode)
+}
 
-	// Extract version from final URL
-	version := version.SemverFromName(filepath.Base(finalURL))
-	if version == "" {
-		return "", fmt.Errorf("could not extract version from URL: %s", finalURL)
+// servedVersion extracts the version a response is for from its final URL,
+// falling back to the served filename without a versioned redirect
+func servedVersion(resp *http.Response) string {
+	if version := version.SemverFromName(path.Base(resp.Request.URL.Path)); version != "" {
+		return version
+	}
+
+	return versionFromContentDisposition(resp.Header.Get("Content-Disposition"))
+}
+
+// checkClockSkew compares the local clock against a server Date header and
+// warns once when they differ by more than clockSkewThreshold
+func (u *Updater) checkClockSkew(date string) {
+	if date == "" {
+		return
 	}
 
-	return version, nil
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+
+	u.clockSkew = time.Until(serverTime)
+
+	if u.clockSkewed() && !u.skewWarned {
+		u.skewWarned = true
+		skew := u.clockSkew
+		if skew < 0 {
+			skew = -skew
+		}
+		direction := "behind"
+		if u.clockSkew < 0 {
+			direction = "ahead of"
+		}
+		u.warn(fmt.Sprintf("local clock is %s %s the server clock, timestamps may be unreliable",
+			skew.Round(time.Second), direction))
+	}
+}
+
+// clockSkewed reports whether the last remote check measured a clock skew
+// beyond clockSkewThreshold
+func (u *Updater) clockSkewed() bool {
+	return u.clockSkew > clockSkewThreshold || u.clockSkew < -clockSkewThreshold
+}
+
+// versionFromContentDisposition extracts the version from the filename of a
+// Content-Disposition header, returning "" if there is none
+func versionFromContentDisposition(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	_, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+
+	return version.SemverFromName(path.Base(params["filename"]))
 }
 
 // GetLocalVersion gets the current local version from the symlink or regular file
@@ -262,6 +531,32 @@ func (u *Updater) GetLocalVersion() (string, error) {
 	return "", nil
 }
 
+// ActiveVersion returns the version the configured latest symlink points at,
+// parsed with the filename pattern. It returns "" when there is no symlink or
+// the launcher is a regular file.
+func (u *Updater) ActiveVersion() (string, error) {
+	symlinkPath := u.getLatestSymlinkPath()
+
+	info, err := os.Lstat(symlinkPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to stat symlink: %v", err)
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", nil
+	}
+
+	target, err := os.Readlink(symlinkPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read symlink: %v", err)
+	}
+
+	return u.versionFromFileName(filepath.Base(target)), nil
+}
+
 // SwitchToVersion switches the symlink to point to a specific version
 func (u *Updater) SwitchToVersion(version string) error {
 	// Construct filename for the version using config pattern
@@ -284,26 +579,97 @@ func (u *Updater) SwitchToVersion(version string) error {
 	}
 
 	// Create new symlink - use relative path if possible
-	symlinkDir := filepath.Dir(symlinkPath)
-	relativePath, err := filepath.Rel(symlinkDir, filePath)
+	if err := os.Symlink(symlinkTarget(symlinkPath, filePath), symlinkPath); err != nil {
+		return fmt.Errorf("failed to create symlink: %v", err)
+	}
+
+	return nil
+}
+
+// symlinkTarget returns the target to store in symlinkPath to point at
+// filePath, relative when possible so links survive moving both together
+func symlinkTarget(symlinkPath, filePath string) string {
+	relativePath, err := filepath.Rel(filepath.Dir(symlinkPath), filePath)
 	if err != nil {
 		// Fallback to absolute path if relative path calculation fails
-		relativePath = filePath
+		return filePath
 	}
+	return relativePath
+}
 
-	if err := os.Symlink(relativePath, symlinkPath); err != nil {
-		return fmt.Errorf("failed to create symlink: %v", err)
+// RelinkResult describes the outcome of a relink
+type RelinkResult struct {
+	Version   string
+	OldTarget string
+	NewTarget string
+	Changed   bool
+}
+
+// Relink recomputes the latest symlink relative to the current download
+// directory, fixing links broken by moving the directory and rewriting
+// absolute links as relative ones
+func (u *Updater) Relink() (RelinkResult, error) {
+	symlinkPath := u.getLatestSymlinkPath()
+
+	oldTarget, err := os.Readlink(symlinkPath)
+	if err != nil {
+		return RelinkResult{}, fmt.Errorf("failed to read symlink: %v", err)
 	}
 
-	return nil
+	version := u.versionFromFileName(filepath.Base(oldTarget))
+	if version == "" {
+		return RelinkResult{}, fmt.Errorf("could not determine version from symlink target: %s", oldTarget)
+	}
+
+	filename := u.GenerateFileName(version)
+	newTarget := symlinkTarget(symlinkPath, u.getDownloadPath(filename))
+
+	result := RelinkResult{
+		Version:   version,
+		OldTarget: oldTarget,
+		NewTarget: newTarget,
+	}
+
+	// Nothing to do if the link already has the right target and resolves
+	if oldTarget == newTarget {
+		if _, err := os.Stat(symlinkPath); err == nil {
+			return result, nil
+		}
+	}
+
+	if err := u.SwitchToVersion(version); err != nil {
+		return RelinkResult{}, err
+	}
+
+	result.Changed = true
+	return result, nil
 }
 
-// CheckForUpdates checks if an update is available
+// CheckForUpdates checks if an update is available, reusing a fresh check
+// cache entry when the cached version is not newer than the local one
 func (u *Updater) CheckForUpdates() (bool, string, error) {
-	// Get remote version
-	remoteVersion, err := u.GetRemoteVersion()
-	if err != nil {
-		return false, "", err
+	return u.checkForUpdates(true)
+}
+
+// CheckForUpdatesLive checks if an update is available against the live
+// remote version, ignoring the check cache
+func (u *Updater) CheckForUpdatesLive() (bool, string, error) {
+	return u.checkForUpdates(false)
+}
+
+func (u *Updater) checkForUpdates(useCache bool) (bool, string, error) {
+	// Get remote version, from the check cache if it is fresh
+	var remoteVersion string
+	var cached bool
+	if useCache {
+		remoteVersion, cached = u.readCheckCache()
+	}
+	if !cached {
+		var err error
+		remoteVersion, err = u.GetRemoteVersion()
+		if err != nil {
+			return false, "", err
+		}
 	}
 
 	// Get local version
@@ -312,13 +678,18 @@ func (u *Updater) CheckForUpdates() (bool, string, error) {
 		return false, "", err
 	}
 
-	// If no local version, update is needed
-	if localVersion == "" {
-		return true, remoteVersion, nil
+	// If no local version, update is needed. Check if remote version is newer otherwise.
+	needsUpdate := localVersion == "" || version.LessThan(localVersion, remoteVersion)
+
+	// An update will hit the network anyway, so make sure it targets the live version
+	if needsUpdate && cached {
+		remoteVersion, err = u.GetRemoteVersion()
+		if err != nil {
+			return false, "", err
+		}
+		needsUpdate = localVersion == "" || version.LessThan(localVersion, remoteVersion)
 	}
 
-	// Check if remote version is newer
-	needsUpdate := version.LessThan(localVersion, remoteVersion)
 	return needsUpdate, remoteVersion, nil
 }
 
@@ -332,6 +703,18 @@ func (u *Updater) SetProgressCallback(callback ProgressCallback) {
 	u.progressCallback = callback
 }
 
+// SetWarningCallback sets the callback function for non-fatal warnings
+func (u *Updater) SetWarningCallback(callback WarningCallback) {
+	u.warningCallback = callback
+}
+
+// warn reports a non-fatal warning through the warning callback
+func (u *Updater) warn(message string) {
+	if u.warningCallback != nil {
+		u.warningCallback(message)
+	}
+}
+
 // getDownloadPath returns the full path for a download file
 func (u *Updater) getDownloadPath(filename string) string {
 	if u.config != nil {
@@ -348,6 +731,57 @@ func (u *Updater) GenerateFileName(version string) string {
 	return fmt.Sprintf("Cursor-%s-x86_64.AppImage", version)
 }
 
+// versionFromFileName extracts the version from a downloaded filename using
+// the config pattern, returning "" for files that aren't version files
+func (u *Updater) versionFromFileName(filename string) string {
+	if u.config != nil {
+		return u.config.VersionFromFileName(filename)
+	}
+	return version.SemverFromName(filename)
+}
+
+// writeStallTimeout returns how long a disk write may block before the
+// download is aborted, 0 when disabled
+func (u *Updater) writeStallTimeout() time.Duration {
+	if u.config != nil {
+		return u.config.WriteStallTimeout
+	}
+	return 0
+}
+
+// getDownloadDir returns the directory downloads are stored in
+func (u *Updater) getDownloadDir() string {
+	if u.config != nil {
+		return u.config.DownloadDir
+	}
+	return u.workDir
+}
+
+// localVersionFiles returns the names of all regular version files in the
+// download directory, sorted by name
+func (u *Updater) localVersionFiles() ([]string, error) {
+	files, err := os.ReadDir(u.getDownloadDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read download directory: %v", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.Type().IsRegular() {
+			continue
+		}
+		if u.versionFromFileName(file.Name()) == "" {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+
+	return names, nil
+}
+
 // getLedgerPath returns the ledger path from config
 func (u *Updater) getLedgerPath() string {
 	if u.config != nil {
@@ -394,18 +828,130 @@ func (u *Updater) ensureDirectories() error {
 	return nil
 }
 
+// QuarantineFile moves a file that failed verification into the configured
+// quarantine directory under a timestamped name and returns the new path.
+// Without a quarantine directory the file is removed and "" is returned.
+func (u *Updater) QuarantineFile(filePath string) (string, error) {
+	if u.config == nil || u.config.QuarantineDir == "" {
+		if err := os.Remove(filePath); err != nil {
+			return "", fmt.Errorf("failed to remove file: %v", err)
+		}
+		return "", nil
+	}
+
+	if err := os.MkdirAll(u.config.QuarantineDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %v", err)
+	}
+
+	name := fmt.Sprintf("%s.%s", filepath.Base(filePath), time.Now().UTC().Format("20060102T150405Z"))
+	quarantinePath := filepath.Join(u.config.QuarantineDir, name)
+
+	// Fall back to copying when the quarantine dir is on another device
+	if err := os.Rename(filePath, quarantinePath); err != nil {
+		if err := copyFile(filePath, quarantinePath); err != nil {
+			return "", fmt.Errorf("failed to quarantine file: %v", err)
+		}
+		if err := os.Remove(filePath); err != nil {
+			return "", fmt.Errorf("failed to remove quarantined file: %v", err)
+		}
+	}
+
+	// Quarantined files are evidence, not something to run
+	if err := os.Chmod(quarantinePath, 0644); err != nil {
+		return "", fmt.Errorf("failed to make quarantined file non-executable: %v", err)
+	}
+
+	return quarantinePath, nil
+}
+
+// copyFile copies src to dst, preserving the file mode
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// HashAlgorithm returns the configured hash algorithm, defaulting to sha256
+func (u *Updater) HashAlgorithm() string {
+	if u.config != nil && u.config.HashAlgorithm != "" {
+		return u.config.HashAlgorithm
+	}
+	return config.HashSHA256
+}
+
 // CalculateSHA256 calculates the SHA256 hash of a file
 func (u *Updater) CalculateSHA256(filepath string) (string, error) {
+	return u.CalculateHashWith(filepath, config.HashSHA256)
+}
+
+// CalculateHash calculates the hash of a file using the configured algorithm
+func (u *Updater) CalculateHash(filepath string) (string, error) {
+	return u.CalculateHashWith(filepath, u.HashAlgorithm())
+}
+
+// CalculateHashWith calculates the hash of a file using the given algorithm
+func (u *Updater) CalculateHashWith(filepath, algorithm string) (string, error) {
+	hash, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
 	file, err := os.Open(filepath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %v", err)
 	}
 	defer file.Close()
 
-	hash := sha256.New()
 	if _, err := io.Copy(hash, file); err != nil {
 		return "", fmt.Errorf("failed to calculate hash: %v", err)
 	}
 
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
+
+// VerifyHash checks that a file matches the expected hash under the given algorithm
+func (u *Updater) VerifyHash(filepath, algorithm, expected string) error {
+	actual, err := u.CalculateHashWith(filepath, algorithm)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%s mismatch: expected %s, got %s", algorithm, expected, actual)
+	}
+
+	return nil
+}
+
+// newHash returns a hash implementation for the given algorithm name
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "", config.HashSHA256:
+		return sha256.New(), nil
+	case config.HashSHA512:
+		return sha512.New(), nil
+	case config.HashBLAKE3:
+		return blake3.New(32, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+	}
+}
@@ -1,8 +1,14 @@
 package updater
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/CoGorm/updateCursor/internal/config"
@@ -122,3 +128,373 @@ func TestUpdaterUsesConfigForSymlinkPath(t *testing.T) {
 		t.Errorf("Expected symlink path %s, got %s", cfg.LatestSymlink, symlinkPath)
 	}
 }
+
+func TestSwitchToVersionWithSymlinkedDownloadDir(t *testing.T) {
+	// Test that switching works when the symlink is configured through a
+	// symlinked directory at a different depth than the real download dir
+	tempDir := t.TempDir()
+	realDir := filepath.Join(tempDir, "storage", "deep", "cursor")
+	linkDir := filepath.Join(tempDir, "home", "Cursor")
+
+	if err := os.MkdirAll(realDir, 0755); err != nil {
+		t.Fatalf("Failed to create real dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(linkDir), 0755); err != nil {
+		t.Fatalf("Failed to create link parent dir: %v", err)
+	}
+	if err := os.Symlink(realDir, linkDir); err != nil {
+		t.Fatalf("Failed to create dir symlink: %v", err)
+	}
+
+	cfg := &config.Config{
+		DownloadDir:     realDir,
+		FileNamePattern: "Cursor-<version>-x86_64.AppImage",
+		LatestSymlink:   filepath.Join(linkDir, "Cursor.AppImage"),
+		LedgerPath:      filepath.Join(tempDir, "config", "cursor-versions.log"),
+	}
+
+	if err := cfg.ExpandPaths(); err != nil {
+		t.Fatalf("Failed to expand config paths: %v", err)
+	}
+
+	versionPath := filepath.Join(linkDir, "Cursor-1.0.0-x86_64.AppImage")
+	if err := os.WriteFile(versionPath, []byte("mock content"), 0755); err != nil {
+		t.Fatalf("Failed to create version file: %v", err)
+	}
+
+	up := NewUpdater("http://example.com", cfg.DownloadDir, cfg)
+
+	if err := up.SwitchToVersion("1.0.0"); err != nil {
+		t.Fatalf("Failed to switch to version: %v", err)
+	}
+
+	// The link target should be relative to the real directory
+	symlinkPath := filepath.Join(linkDir, "Cursor.AppImage")
+	target, err := os.Readlink(symlinkPath)
+	if err != nil {
+		t.Fatalf("Failed to read symlink: %v", err)
+	}
+
+	if target != "Cursor-1.0.0-x86_64.AppImage" {
+		t.Errorf("Expected symlink target Cursor-1.0.0-x86_64.AppImage, got %s", target)
+	}
+
+	// The symlink should resolve through both the link dir and the real dir
+	content, err := os.ReadFile(symlinkPath)
+	if err != nil {
+		t.Fatalf("Expected symlink to resolve, got: %v", err)
+	}
+
+	if string(content) != "mock content" {
+		t.Errorf("Expected resolved content 'mock content', got %q", string(content))
+	}
+
+	version, err := up.GetLocalVersion()
+	if err != nil {
+		t.Fatalf("Failed to get local version: %v", err)
+	}
+
+	if version != "1.0.0" {
+		t.Errorf("Expected local version 1.0.0, got %s", version)
+	}
+}
+
+func TestRelinkAfterMovingDownloadDir(t *testing.T) {
+	tests := []struct {
+		name string
+		// link creates the symlink before the move, given the old download dir
+		link func(t *testing.T, oldDir, symlinkPath string)
+		// symlinkPath returns the symlink location given the temp and new download dir
+		symlinkPath func(tempDir, newDir string) string
+	}{
+		{
+			name: "absolute link inside moved dir",
+			link: func(t *testing.T, oldDir, symlinkPath string) {
+				if err := os.Symlink(filepath.Join(oldDir, "Cursor-1.0.0-x86_64.AppImage"), symlinkPath); err != nil {
+					t.Fatalf("Failed to create symlink: %v", err)
+				}
+			},
+			symlinkPath: func(tempDir, newDir string) string { return filepath.Join(newDir, "Cursor.AppImage") },
+		},
+		{
+			name: "relative link outside moved dir",
+			link: func(t *testing.T, oldDir, symlinkPath string) {
+				if err := os.Symlink(filepath.Join("..", "old", "Cursor-1.0.0-x86_64.AppImage"), symlinkPath); err != nil {
+					t.Fatalf("Failed to create symlink: %v", err)
+				}
+			},
+			symlinkPath: func(tempDir, newDir string) string { return filepath.Join(tempDir, "bin", "Cursor.AppImage") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			oldDir := filepath.Join(tempDir, "old")
+			newDir := filepath.Join(tempDir, "new")
+
+			if err := os.MkdirAll(oldDir, 0755); err != nil {
+				t.Fatalf("Failed to create old dir: %v", err)
+			}
+			if err := os.MkdirAll(filepath.Join(tempDir, "bin"), 0755); err != nil {
+				t.Fatalf("Failed to create bin dir: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(oldDir, "Cursor-1.0.0-x86_64.AppImage"), []byte("mock content"), 0755); err != nil {
+				t.Fatalf("Failed to create version file: %v", err)
+			}
+
+			// Create the link where it will be after the move
+			symlinkPath := tt.symlinkPath(tempDir, newDir)
+			oldSymlinkPath := strings.Replace(symlinkPath, newDir, oldDir, 1)
+			tt.link(t, oldDir, oldSymlinkPath)
+
+			// Move the whole download dir
+			if err := os.Rename(oldDir, newDir); err != nil {
+				t.Fatalf("Failed to move download dir: %v", err)
+			}
+
+			if _, err := os.Stat(symlinkPath); err == nil {
+				t.Fatal("Expected symlink to be broken after the move")
+			}
+
+			cfg := &config.Config{
+				DownloadDir:     newDir,
+				FileNamePattern: "Cursor-<version>-x86_64.AppImage",
+				LatestSymlink:   symlinkPath,
+				LedgerPath:      filepath.Join(tempDir, "cursor-versions.log"),
+			}
+
+			up := NewUpdater("http://example.com", cfg.DownloadDir, cfg)
+
+			result, err := up.Relink()
+			if err != nil {
+				t.Fatalf("Failed to relink: %v", err)
+			}
+
+			if !result.Changed || result.Version != "1.0.0" {
+				t.Errorf("Expected relink of 1.0.0 to change the link, got %+v", result)
+			}
+
+			content, err := os.ReadFile(symlinkPath)
+			if err != nil {
+				t.Fatalf("Expected symlink to resolve after relink, got: %v", err)
+			}
+
+			if string(content) != "mock content" {
+				t.Errorf("Expected resolved content 'mock content', got %q", string(content))
+			}
+
+			target, err := os.Readlink(symlinkPath)
+			if err != nil {
+				t.Fatalf("Failed to read symlink: %v", err)
+			}
+
+			if filepath.IsAbs(target) {
+				t.Errorf("Expected relative symlink target, got %s", target)
+			}
+
+			// A second relink has nothing to do
+			result, err = up.Relink()
+			if err != nil {
+				t.Fatalf("Failed to relink again: %v", err)
+			}
+
+			if result.Changed {
+				t.Errorf("Expected second relink to be a no-op, got %+v", result)
+			}
+		})
+	}
+}
+
+// newSharedCacheServer serves Cursor 1.0.0 and counts downloads of the file
+func newSharedCacheServer(t *testing.T, downloads *int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/stable/linux-x64":
+			http.Redirect(w, r, "/download/Cursor-1.0.0-x86_64.AppImage", http.StatusFound)
+		case "/download/Cursor-1.0.0-x86_64.AppImage":
+			if r.Method == http.MethodGet {
+				atomic.AddInt32(downloads, 1)
+			}
+			w.Write([]byte("downloaded content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestDownloadCursorUsesSharedCache(t *testing.T) {
+	sha256Hex := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name            string
+		mode            os.FileMode
+		hashFile        string // contents of the .sha256 file, "" for none
+		expectDownloads int32
+		expectContent   string
+		expectWarning   bool
+	}{
+		{name: "verified", mode: 0755, hashFile: sha256Hex("cached content") + "  Cursor-1.0.0-x86_64.AppImage\n", expectContent: "cached content"},
+		{name: "verified not executable", mode: 0644, hashFile: sha256Hex("cached content") + "  Cursor-1.0.0-x86_64.AppImage\n", expectContent: "cached content"},
+		{name: "tampered", mode: 0755, hashFile: sha256Hex("original content") + "  Cursor-1.0.0-x86_64.AppImage\n", expectDownloads: 1, expectContent: "downloaded content", expectWarning: true},
+		{name: "no recorded hash", mode: 0755, expectDownloads: 1, expectContent: "downloaded content", expectWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var downloads int32
+			server := newSharedCacheServer(t, &downloads)
+
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				DownloadDir:     filepath.Join(tempDir, "downloads"),
+				FileNamePattern: "Cursor-<version>-x86_64.AppImage",
+				LatestSymlink:   filepath.Join(tempDir, "downloads", "Cursor.AppImage"),
+				LedgerPath:      filepath.Join(tempDir, "cursor-versions.log"),
+				SharedCacheDir:  filepath.Join(tempDir, "shared"),
+			}
+
+			if err := os.MkdirAll(cfg.SharedCacheDir, 0755); err != nil {
+				t.Fatalf("Failed to create shared cache: %v", err)
+			}
+			cachedPath := filepath.Join(cfg.SharedCacheDir, "Cursor-1.0.0-x86_64.AppImage")
+			if err := os.WriteFile(cachedPath, []byte("cached content"), tt.mode); err != nil {
+				t.Fatalf("Failed to create cached file: %v", err)
+			}
+			if tt.hashFile != "" {
+				if err := os.WriteFile(cachedPath+".sha256", []byte(tt.hashFile), 0644); err != nil {
+					t.Fatalf("Failed to create hash file: %v", err)
+				}
+			}
+
+			up := NewUpdater(server.URL+"/download/stable/linux-x64", cfg.DownloadDir, cfg)
+
+			var warnings []string
+			up.SetWarningCallback(func(message string) {
+				warnings = append(warnings, message)
+			})
+
+			filename, err := up.DownloadCursor()
+			if err != nil {
+				t.Fatalf("Failed to download Cursor: %v", err)
+			}
+
+			if got := atomic.LoadInt32(&downloads); got != tt.expectDownloads {
+				t.Errorf("Expected %d downloads, got %d", tt.expectDownloads, got)
+			}
+
+			if tt.expectWarning != (len(warnings) > 0) {
+				t.Errorf("Expected warning: %v, got %v", tt.expectWarning, warnings)
+			}
+
+			localPath := filepath.Join(cfg.DownloadDir, filename)
+			content, err := os.ReadFile(localPath)
+			if err != nil {
+				t.Fatalf("Failed to read local file: %v", err)
+			}
+
+			if string(content) != tt.expectContent {
+				t.Errorf("Expected %q, got %q", tt.expectContent, string(content))
+			}
+
+			info, err := os.Stat(localPath)
+			if err != nil {
+				t.Fatalf("Failed to stat local file: %v", err)
+			}
+
+			if info.Mode().Perm()&0100 == 0 {
+				t.Errorf("Expected local file to be executable, got %s", info.Mode())
+			}
+
+			// The private copy must never share an inode with the cache
+			cachedInfo, err := os.Stat(cachedPath)
+			if err != nil {
+				t.Fatalf("Failed to stat cached file: %v", err)
+			}
+
+			if os.SameFile(info, cachedInfo) {
+				t.Error("Expected the local file to be a copy, not a hardlink to the shared cache")
+			}
+		})
+	}
+}
+
+func TestDownloadCursorPopulatesSharedCache(t *testing.T) {
+	var downloads int32
+	server := newSharedCacheServer(t, &downloads)
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		DownloadDir:         filepath.Join(tempDir, "downloads"),
+		FileNamePattern:     "Cursor-<version>-x86_64.AppImage",
+		LatestSymlink:       filepath.Join(tempDir, "downloads", "Cursor.AppImage"),
+		LedgerPath:          filepath.Join(tempDir, "cursor-versions.log"),
+		SharedCacheDir:      filepath.Join(tempDir, "shared"),
+		SharedCachePopulate: true,
+	}
+
+	if err := os.MkdirAll(cfg.SharedCacheDir, 0755); err != nil {
+		t.Fatalf("Failed to create shared cache: %v", err)
+	}
+
+	up := NewUpdater(server.URL+"/download/stable/linux-x64", cfg.DownloadDir, cfg)
+
+	filename, err := up.DownloadCursor()
+	if err != nil {
+		t.Fatalf("Failed to download Cursor: %v", err)
+	}
+
+	if atomic.LoadInt32(&downloads) != 1 {
+		t.Errorf("Expected one download with an empty shared cache, got %d", downloads)
+	}
+
+	// Downloads are only shared once the caller has installed them
+	if _, err := os.Stat(filepath.Join(cfg.SharedCacheDir, filename)); !os.IsNotExist(err) {
+		t.Errorf("Expected the download not to be shared before it is installed, stat returned: %v", err)
+	}
+
+	if err := up.PopulateSharedCache(filename, false); err != nil {
+		t.Fatalf("Failed to populate shared cache: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(cfg.SharedCacheDir, "Cursor-1.0.0-x86_64.AppImage"))
+	if err != nil {
+		t.Fatalf("Expected shared cache to be populated, got: %v", err)
+	}
+
+	if string(content) != "downloaded content" {
+		t.Errorf("Expected downloaded content in shared cache, got %q", string(content))
+	}
+
+	cachedPath := filepath.Join(cfg.SharedCacheDir, "Cursor-1.0.0-x86_64.AppImage")
+	recorded, err := readSharedCacheHash(cachedPath)
+	if err != nil {
+		t.Fatalf("Expected a recorded hash for the cached file, got: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte("downloaded content"))
+	if recorded != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected recorded hash %x, got %s", sum, recorded)
+	}
+
+	localInfo, err := os.Stat(filepath.Join(cfg.DownloadDir, "Cursor-1.0.0-x86_64.AppImage"))
+	if err != nil {
+		t.Fatalf("Failed to stat local file: %v", err)
+	}
+	cachedInfo, err := os.Stat(cachedPath)
+	if err != nil {
+		t.Fatalf("Failed to stat cached file: %v", err)
+	}
+
+	if os.SameFile(localInfo, cachedInfo) {
+		t.Error("Expected the shared cache to hold a copy, not a hardlink to the local file")
+	}
+}
@@ -1,14 +1,43 @@
 package updater
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/CoGorm/updateCursor/internal/config"
 )
 
+// newTestUpdater creates an updater for downloadURL with its download dir,
+// symlink and ledger in a temp dir, and a file for each of versions
+func newTestUpdater(t *testing.T, downloadURL string, versions ...string) (*Updater, *config.Config) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	cfg := config.NewConfig()
+	cfg.DownloadDir = filepath.Join(tempDir, "downloads")
+	cfg.LatestSymlink = filepath.Join(cfg.DownloadDir, "Cursor.AppImage")
+	cfg.LedgerPath = filepath.Join(tempDir, "cursor-versions.log")
+
+	if err := os.MkdirAll(cfg.DownloadDir, 0755); err != nil {
+		t.Fatalf("Failed to create download dir: %v", err)
+	}
+
+	for _, ver := range versions {
+		path := filepath.Join(cfg.DownloadDir, cfg.GenerateFileName(ver))
+		if err := os.WriteFile(path, []byte("mock content"), 0755); err != nil {
+			t.Fatalf("Failed to create version file: %v", err)
+		}
+	}
+
+	return NewUpdater(downloadURL, cfg.DownloadDir, cfg), cfg
+}
+
 func TestDownloadCursor(t *testing.T) {
 	// Create a mock HTTP server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -464,3 +493,324 @@ func TestUpdaterEnsuresDirectoriesExist(t *testing.T) {
 		t.Errorf("Expected download directory to exist: %s", downloadDir)
 	}
 }
+
+func TestCalculateHashAlgorithms(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	err := os.WriteFile(testFile, []byte("Hello, World!"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		algorithm string
+		expected  string
+	}{
+		{algorithm: config.HashSHA256, expected: "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f"},
+		{algorithm: config.HashSHA512, expected: "374d794a95cdcfd8b35993185fef9ba368f160d8daf432d08ba9f1ed1e5abe6cc69291e0fa2fe0006a52570ef18c19def4e617c33ce52ef0a6e5fbe318cb0387"},
+		{algorithm: config.HashBLAKE3, expected: "288a86a79f20a3d6dccdca7713beaed178798296bdfa7913fa2a62d9727bf8f8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.HashAlgorithm = tt.algorithm
+			updater := NewUpdater("http://example.com", tempDir, cfg)
+
+			hash, err := updater.CalculateHash(testFile)
+			if err != nil {
+				t.Fatalf("Failed to calculate hash: %v", err)
+			}
+
+			if hash != tt.expected {
+				t.Errorf("Expected %s hash %s, got %s", tt.algorithm, tt.expected, hash)
+			}
+
+			// The computed hash should verify under the same algorithm
+			if err := updater.VerifyHash(testFile, tt.algorithm, hash); err != nil {
+				t.Errorf("Expected hash to verify, got: %v", err)
+			}
+
+			// Modified content must fail verification
+			modified := filepath.Join(tempDir, "modified-"+tt.algorithm)
+			if err := os.WriteFile(modified, []byte("Hello, World?"), 0644); err != nil {
+				t.Fatalf("Failed to create modified file: %v", err)
+			}
+			if err := updater.VerifyHash(modified, tt.algorithm, hash); err == nil {
+				t.Error("Expected verification of modified file to fail")
+			}
+		})
+	}
+}
+
+func TestCalculateHashUnsupportedAlgorithm(t *testing.T) {
+	tempDir := t.TempDir()
+
+	updater := NewUpdater("http://example.com", tempDir, nil)
+
+	if updater.HashAlgorithm() != config.HashSHA256 {
+		t.Errorf("Expected default hash algorithm %s, got %s", config.HashSHA256, updater.HashAlgorithm())
+	}
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("Hello, World!"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if _, err := updater.CalculateHashWith(testFile, "md5"); err == nil {
+		t.Error("Expected error for unsupported hash algorithm")
+	}
+}
+
+func TestGetRemoteVersionWithoutRedirect(t *testing.T) {
+	// Create a mock HTTP server that serves the file directly
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download/stable/linux-x64" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", `attachment; filename="Cursor-1.3.0-x86_64.AppImage"`)
+			w.Write([]byte("mock cursor appimage content"))
+		} else {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+
+	updater := NewUpdater(server.URL+"/download/stable/linux-x64", tempDir, nil)
+
+	version, err := updater.GetRemoteVersion()
+	if err != nil {
+		t.Fatalf("Failed to get remote version: %v", err)
+	}
+
+	expectedVersion := "1.3.0"
+	if version != expectedVersion {
+		t.Errorf("Expected version %s, got %s", expectedVersion, version)
+	}
+
+	// Downloading should also work without a redirect
+	filename, err := updater.DownloadCursor()
+	if err != nil {
+		t.Fatalf("Failed to download Cursor: %v", err)
+	}
+
+	if filename != "Cursor-1.3.0-x86_64.AppImage" {
+		t.Errorf("Expected filename Cursor-1.3.0-x86_64.AppImage, got %s", filename)
+	}
+}
+
+func TestGetRemoteVersionWithoutRedirectOrFilename(t *testing.T) {
+	// Create a mock HTTP server that serves the file directly with no filename
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("mock cursor appimage content"))
+	}))
+	defer server.Close()
+
+	updater := NewUpdater(server.URL+"/download/stable/linux-x64", "/tmp", nil)
+
+	_, err := updater.GetRemoteVersion()
+	if err == nil {
+		t.Fatal("Expected error when no version can be determined")
+	}
+
+	if !strings.Contains(err.Error(), "Content-Disposition") {
+		t.Errorf("Expected error to mention Content-Disposition, got: %v", err)
+	}
+}
+
+func TestGetRemoteVersionWarnsOnClockSkew(t *testing.T) {
+	tests := []struct {
+		name        string
+		offset      time.Duration
+		expectWarn  bool
+		description string
+	}{
+		{name: "server ahead", offset: 2 * time.Hour, expectWarn: true, description: "behind"},
+		{name: "server behind", offset: -2 * time.Hour, expectWarn: true, description: "ahead of"},
+		{name: "in sync", offset: 0, expectWarn: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Date", time.Now().Add(tt.offset).UTC().Format(http.TimeFormat))
+				if r.URL.Path == "/download/stable/linux-x64" {
+					http.Redirect(w, r, "/download/Cursor-1.2.3-x86_64.AppImage", http.StatusFound)
+				}
+			}))
+			defer server.Close()
+
+			updater := NewUpdater(server.URL+"/download/stable/linux-x64", "/tmp", nil)
+
+			var warnings []string
+			updater.SetWarningCallback(func(message string) {
+				warnings = append(warnings, message)
+			})
+
+			// A run checks the remote several times but only warns once
+			for i := 0; i < 2; i++ {
+				if _, err := updater.GetRemoteVersion(); err != nil {
+					t.Fatalf("Failed to get remote version: %v", err)
+				}
+			}
+
+			if !tt.expectWarn {
+				if len(warnings) != 0 {
+					t.Errorf("Expected no warnings, got %v", warnings)
+				}
+				return
+			}
+
+			if len(warnings) != 1 || !strings.Contains(warnings[0], "clock") || !strings.Contains(warnings[0], tt.description) {
+				t.Errorf("Expected one clock skew warning mentioning %q, got %v", tt.description, warnings)
+			}
+
+			skew := updater.clockSkew
+			if (tt.offset > 0 && skew < time.Hour) || (tt.offset < 0 && skew > -time.Hour) {
+				t.Errorf("Expected clock skew close to %s, got %s", tt.offset, skew)
+			}
+		})
+	}
+}
+
+func TestGetRemoteVersionCached(t *testing.T) {
+	newVersionServer := func(ver string, hits *int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/download/stable/linux-x64" {
+				*hits++
+				http.Redirect(w, r, "/download/Cursor-"+ver+"-x86_64.AppImage", http.StatusFound)
+			}
+		}))
+	}
+
+	var firstHits, secondHits int
+	first := newVersionServer("1.2.3", &firstHits)
+	defer first.Close()
+	second := newVersionServer("1.3.0", &secondHits)
+	defer second.Close()
+
+	tempDir := t.TempDir()
+	cachePath := filepath.Join(tempDir, "check-cache.json")
+
+	updater := NewUpdater(first.URL+"/download/stable/linux-x64", tempDir, nil)
+	updater.SetCheckCache(cachePath, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		version, err := updater.GetRemoteVersionCached()
+		if err != nil {
+			t.Fatalf("Failed to get remote version: %v", err)
+		}
+		if version != "1.2.3" {
+			t.Errorf("Expected version 1.2.3, got %s", version)
+		}
+	}
+
+	if firstHits != 1 {
+		t.Errorf("Expected the second check to be served from the cache, got %d requests", firstHits)
+	}
+
+	// A different download URL must not reuse the stamp written for the first one
+	updater = NewUpdater(second.URL+"/download/stable/linux-x64", tempDir, nil)
+	updater.SetCheckCache(cachePath, time.Hour)
+
+	version, err := updater.GetRemoteVersionCached()
+	if err != nil {
+		t.Fatalf("Failed to get remote version: %v", err)
+	}
+	if version != "1.3.0" {
+		t.Errorf("Expected version 1.3.0 after changing the URL, got %s", version)
+	}
+	if secondHits != 1 {
+		t.Errorf("Expected a fresh check after changing the URL, got %d requests", secondHits)
+	}
+}
+
+func TestCheckCacheNotWrittenWithSkewedClock(t *testing.T) {
+	tests := []struct {
+		name        string
+		offset      time.Duration
+		expectCache bool
+	}{
+		{name: "in sync", offset: 0, expectCache: true},
+		{name: "skewed", offset: 2 * time.Hour, expectCache: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Date", time.Now().Add(tt.offset).UTC().Format(http.TimeFormat))
+				if r.URL.Path == "/download/stable/linux-x64" {
+					http.Redirect(w, r, "/download/Cursor-1.2.3-x86_64.AppImage", http.StatusFound)
+				}
+			}))
+			defer server.Close()
+
+			tempDir := t.TempDir()
+			cachePath := filepath.Join(tempDir, "check-cache.json")
+
+			updater := NewUpdater(server.URL+"/download/stable/linux-x64", tempDir, nil)
+			updater.SetCheckCache(cachePath, time.Hour)
+			updater.SetWarningCallback(func(string) {})
+
+			if _, err := updater.GetRemoteVersion(); err != nil {
+				t.Fatalf("Failed to get remote version: %v", err)
+			}
+
+			_, err := os.Stat(cachePath)
+			if tt.expectCache && err != nil {
+				t.Errorf("Expected the check to be cached, got: %v", err)
+			}
+			if !tt.expectCache && !os.IsNotExist(err) {
+				t.Errorf("Expected no cache stamp with a skewed clock, stat returned: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetRemoteVersionCachedIgnoresStaleStamps(t *testing.T) {
+	tests := []struct {
+		name      string
+		checkedAt time.Time
+	}{
+		{name: "expired", checkedAt: time.Now().Add(-2 * time.Hour)},
+		{name: "in the future", checkedAt: time.Now().Add(2 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/download/stable/linux-x64" {
+					hits++
+					http.Redirect(w, r, "/download/Cursor-1.3.0-x86_64.AppImage", http.StatusFound)
+				}
+			}))
+			defer server.Close()
+
+			tempDir := t.TempDir()
+			updater := NewUpdater(server.URL+"/download/stable/linux-x64", tempDir, nil)
+			updater.SetCheckCache(filepath.Join(tempDir, "check-cache.json"), time.Hour)
+
+			// Seed the cache with an old result for the same URL
+			cache := checkCache{Key: updater.checkCacheKey(), CheckedAt: tt.checkedAt, RemoteVersion: "1.2.3"}
+			data, err := json.Marshal(cache)
+			if err != nil {
+				t.Fatalf("Failed to marshal cache: %v", err)
+			}
+			if err := os.WriteFile(updater.checkCachePath, data, 0644); err != nil {
+				t.Fatalf("Failed to write cache: %v", err)
+			}
+
+			version, err := updater.GetRemoteVersionCached()
+			if err != nil {
+				t.Fatalf("Failed to get remote version: %v", err)
+			}
+			if version != "1.3.0" || hits != 1 {
+				t.Errorf("Expected a fresh check returning 1.3.0, got %s after %d requests", version, hits)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package updater
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/CoGorm/updateCursor/internal/config"
+)
+
+// Metadata describes the downloaded file handed to verifiers
+type Metadata struct {
+	Version       string
+	Filename      string
+	HashAlgorithm string
+	Hash          string
+}
+
+// Verifier checks a downloaded file before it is installed. Verify returns
+// nil when the file passes and an error giving the reason otherwise.
+type Verifier interface {
+	Name() string
+	Verify(filePath string, meta Metadata) error
+}
+
+// VerifierFactory creates a verifier from the configuration
+type VerifierFactory func(cfg *config.Config) (Verifier, error)
+
+// verifierRegistry holds the verifiers that can be enabled by name
+var verifierRegistry = map[string]VerifierFactory{
+	"command": newCommandVerifier,
+}
+
+// RegisterVerifier makes a verifier available by name to the verifiers
+// config setting, replacing any verifier registered under the same name
+func RegisterVerifier(name string, factory VerifierFactory) {
+	verifierRegistry[name] = factory
+}
+
+// NewVerifiers creates the named verifiers in the given order
+func NewVerifiers(names []string, cfg *config.Config) ([]Verifier, error) {
+	verifiers := make([]Verifier, 0, len(names))
+	for _, name := range names {
+		factory, ok := verifierRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown verifier: %s (available: %s)", name, strings.Join(registeredVerifiers(), ", "))
+		}
+
+		verifier, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create verifier %s: %v", name, err)
+		}
+		verifiers = append(verifiers, verifier)
+	}
+
+	return verifiers, nil
+}
+
+// registeredVerifiers returns the sorted names of all registered verifiers
+func registeredVerifiers() []string {
+	names := make([]string, 0, len(verifierRegistry))
+	for name := range verifierRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// VerificationError is returned when a verifier rejects a file
+type VerificationError struct {
+	Verifier string
+	Filename string
+	Err      error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("verifier %s rejected %s: %v", e.Verifier, e.Filename, e.Err)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// SetVerifiers sets the verifiers run before a version is installed
+func (u *Updater) SetVerifiers(verifiers ...Verifier) {
+	u.verifiers = verifiers
+}
+
+// VerifyFile runs the verifiers in order, stopping at the first failure
+func (u *Updater) VerifyFile(filePath string, meta Metadata) error {
+	for _, verifier := range u.verifiers {
+		if err := verifier.Verify(filePath, meta); err != nil {
+			return &VerificationError{Verifier: verifier.Name(), Filename: meta.Filename, Err: err}
+		}
+	}
+	return nil
+}
+
+// InstallVersion verifies a downloaded version and points the symlink at it
+// only when every verifier passes. A rejection is returned as a
+// *VerificationError.
+func (u *Updater) InstallVersion(meta Metadata) error {
+	if err := u.VerifyFile(u.getDownloadPath(meta.Filename), meta); err != nil {
+		return err
+	}
+	return u.SwitchToVersion(meta.Version)
+}
+
+// commandVerifier runs an external program, such as a GPG or malware scan
+// wrapper, with the file path as its last argument. A non-zero exit status
+// fails verification with the program's output as the reason.
+type commandVerifier struct {
+	command []string
+}
+
+func newCommandVerifier(cfg *config.Config) (Verifier, error) {
+	if cfg == nil || len(cfg.VerifyCommand) == 0 {
+		return nil, fmt.Errorf("verify_command is not set")
+	}
+	return &commandVerifier{command: cfg.VerifyCommand}, nil
+}
+
+// Name implements Verifier
+func (v *commandVerifier) Name() string {
+	return "command"
+}
+
+// Verify implements Verifier. The metadata is passed in UPDATECURSOR_*
+// environment variables.
+func (v *commandVerifier) Verify(filePath string, meta Metadata) error {
+	args := append(append([]string{}, v.command[1:]...), filePath)
+	cmd := exec.Command(v.command[0], args...)
+	cmd.Env = append(os.Environ(),
+		"UPDATECURSOR_FILE="+filePath,
+		"UPDATECURSOR_VERSION="+meta.Version,
+		"UPDATECURSOR_HASH="+meta.Hash,
+		"UPDATECURSOR_HASH_ALGORITHM="+meta.HashAlgorithm,
+	)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if reason := strings.TrimSpace(output.String()); reason != "" {
+			return fmt.Errorf("%s: %v", reason, err)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package updater
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CoGorm/updateCursor/internal/config"
+)
+
+// stubVerifier records its calls and fails with err when set
+type stubVerifier struct {
+	name  string
+	err   error
+	calls *[]string
+}
+
+func (v stubVerifier) Name() string {
+	return v.name
+}
+
+func (v stubVerifier) Verify(filePath string, meta Metadata) error {
+	*v.calls = append(*v.calls, v.name)
+	return v.err
+}
+
+func TestInstallVersionRunsAllVerifiers(t *testing.T) {
+	up, cfg := newTestUpdater(t, "http://example.com", "1.0.0")
+
+	var calls []string
+	up.SetVerifiers(
+		stubVerifier{name: "gpg", calls: &calls},
+		stubVerifier{name: "scanner", calls: &calls},
+		stubVerifier{name: "policy", calls: &calls},
+	)
+
+	meta := Metadata{Version: "1.0.0", Filename: cfg.GenerateFileName("1.0.0")}
+	if err := up.InstallVersion(meta); err != nil {
+		t.Fatalf("Failed to install version: %v", err)
+	}
+
+	if strings.Join(calls, ",") != "gpg,scanner,policy" {
+		t.Errorf("Expected every verifier to run in order, got %v", calls)
+	}
+
+	if _, err := os.Readlink(cfg.LatestSymlink); err != nil {
+		t.Errorf("Expected the symlink to be created: %v", err)
+	}
+}
+
+func TestInstallVersionStopsAtFirstFailure(t *testing.T) {
+	up, cfg := newTestUpdater(t, "http://example.com", "1.0.0")
+
+	var calls []string
+	up.SetVerifiers(
+		stubVerifier{name: "gpg", calls: &calls},
+		stubVerifier{name: "scanner", err: errors.New("signature of known malware"), calls: &calls},
+		stubVerifier{name: "policy", calls: &calls},
+	)
+
+	meta := Metadata{Version: "1.0.0", Filename: cfg.GenerateFileName("1.0.0")}
+	err := up.InstallVersion(meta)
+	if err == nil {
+		t.Fatal("Expected the failing verifier to block the install")
+	}
+
+	if !strings.Contains(err.Error(), "scanner") || !strings.Contains(err.Error(), "signature of known malware") {
+		t.Errorf("Expected the error to name the verifier and its reason, got: %v", err)
+	}
+
+	var verifyErr *VerificationError
+	if !errors.As(err, &verifyErr) || verifyErr.Verifier != "scanner" {
+		t.Errorf("Expected a VerificationError from scanner, got: %#v", err)
+	}
+
+	if strings.Join(calls, ",") != "gpg,scanner" {
+		t.Errorf("Expected verification to stop at the first failure, got %v", calls)
+	}
+
+	if _, err := os.Lstat(cfg.LatestSymlink); !os.IsNotExist(err) {
+		t.Errorf("Expected no symlink after a failed verification, lstat returned: %v", err)
+	}
+}
+
+func TestNewVerifiers(t *testing.T) {
+	RegisterVerifier("test-stub", func(cfg *config.Config) (Verifier, error) {
+		return stubVerifier{name: "test-stub", calls: new([]string)}, nil
+	})
+	defer delete(verifierRegistry, "test-stub")
+
+	cfg := config.NewConfig()
+	cfg.VerifyCommand = []string{"true"}
+
+	verifiers, err := NewVerifiers([]string{"test-stub", "command"}, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create verifiers: %v", err)
+	}
+
+	if len(verifiers) != 2 || verifiers[0].Name() != "test-stub" || verifiers[1].Name() != "command" {
+		t.Errorf("Expected test-stub and command verifiers in order, got %v", verifiers)
+	}
+
+	if _, err := NewVerifiers([]string{"missing"}, cfg); err == nil || !strings.Contains(err.Error(), "unknown verifier") {
+		t.Errorf("Expected an unknown verifier error, got: %v", err)
+	}
+
+	cfg.VerifyCommand = nil
+	if _, err := NewVerifiers([]string{"command"}, cfg); err == nil {
+		t.Error("Expected an error for the command verifier without verify_command")
+	}
+}
+
+func TestCommandVerifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		expectErr string
+	}{
+		{name: "pass", script: `test "$UPDATECURSOR_VERSION" = 1.0.0 && test -f "$1"`},
+		{name: "fail with reason", script: `echo "not signed by release key"; exit 1`, expectErr: "not signed by release key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up, cfg := newTestUpdater(t, "http://example.com", "1.0.0")
+			cfg.VerifyCommand = []string{"sh", "-c", tt.script, "verify"}
+
+			verifiers, err := NewVerifiers([]string{"command"}, cfg)
+			if err != nil {
+				t.Fatalf("Failed to create verifiers: %v", err)
+			}
+			up.SetVerifiers(verifiers...)
+
+			meta := Metadata{Version: "1.0.0", Filename: cfg.GenerateFileName("1.0.0")}
+			err = up.VerifyFile(filepath.Join(cfg.DownloadDir, meta.Filename), meta)
+			if tt.expectErr == "" {
+				if err != nil {
+					t.Errorf("Expected verification to pass, got: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
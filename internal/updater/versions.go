@@ -0,0 +1,116 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/CoGorm/updateCursor/internal/version"
+)
+
+// VersionStatus describes where a version is available
+type VersionStatus struct {
+	Version string `json:"version"`
+	Remote  bool   `json:"remote"`
+	Local   bool   `json:"local"`
+	Active  bool   `json:"active"`
+}
+
+// ListRemoteVersions returns the versions available for download. With a
+// configured versions_url the full list is fetched from it, otherwise only
+// the latest version can be discovered.
+func (u *Updater) ListRemoteVersions() ([]string, error) {
+	if u.config == nil || u.config.VersionsURL == "" {
+		latest, err := u.GetRemoteVersion()
+		if err != nil {
+			return nil, err
+		}
+		return []string{latest}, nil
+	}
+
+	resp, err := http.Get(u.config.VersionsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch version list: %v", err)
+	}
+	defer resp.Body.Close()
+
+	u.checkClockSkew(resp.Header.Get("Date"))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch version list: status %d", resp.StatusCode)
+	}
+
+	var versions []string
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("failed to parse version list: %v", err)
+	}
+
+	return versions, nil
+}
+
+// LocalVersions returns the versions downloaded to the download directory
+func (u *Updater) LocalVersions() ([]string, error) {
+	names, err := u.localVersionFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(names))
+	for _, name := range names {
+		versions = append(versions, u.versionFromFileName(name))
+	}
+
+	return versions, nil
+}
+
+// MergeVersions combines remote, local and active version information into
+// one entry per version, sorted from oldest to newest
+func MergeVersions(remote, local []string, active string) []VersionStatus {
+	byVersion := make(map[string]*VersionStatus)
+	status := func(v string) *VersionStatus {
+		if s, ok := byVersion[v]; ok {
+			return s
+		}
+		s := &VersionStatus{Version: v}
+		byVersion[v] = s
+		return s
+	}
+
+	for _, v := range remote {
+		status(v).Remote = true
+	}
+	for _, v := range local {
+		status(v).Local = true
+	}
+	if active != "" {
+		status(active).Active = true
+	}
+
+	merged := make([]VersionStatus, 0, len(byVersion))
+	for _, s := range byVersion {
+		merged = append(merged, *s)
+	}
+
+	// Unparseable versions sort last, by name
+	sort.Slice(merged, func(i, j int) bool {
+		a, b := merged[i].Version, merged[j].Version
+		_, _, _, errA := version.ParseSemver(a)
+		_, _, _, errB := version.ParseSemver(b)
+		switch {
+		case errA != nil || errB != nil:
+			if (errA == nil) != (errB == nil) {
+				return errA == nil
+			}
+			return a < b
+		case version.LessThan(a, b):
+			return true
+		case version.LessThan(b, a):
+			return false
+		default:
+			return a < b
+		}
+	})
+
+	return merged
+}
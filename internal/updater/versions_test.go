@@ -0,0 +1,91 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/CoGorm/updateCursor/internal/config"
+)
+
+func TestMergeVersions(t *testing.T) {
+	remote := []string{"1.3.0", "1.2.0", "1.10.0"}
+	local := []string{"1.2.0", "1.1.0"}
+
+	merged := MergeVersions(remote, local, "1.2.0")
+
+	expected := []VersionStatus{
+		{Version: "1.1.0", Local: true},
+		{Version: "1.2.0", Remote: true, Local: true, Active: true},
+		{Version: "1.3.0", Remote: true},
+		{Version: "1.10.0", Remote: true},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, merged)
+	}
+}
+
+func TestMergeVersionsWithoutRemote(t *testing.T) {
+	merged := MergeVersions(nil, []string{"1.1.0"}, "1.0.0")
+
+	// An active version whose file is gone still shows up
+	expected := []VersionStatus{
+		{Version: "1.0.0", Active: true},
+		{Version: "1.1.0", Local: true},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, merged)
+	}
+}
+
+func TestListRemoteVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/stable/linux-x64":
+			http.Redirect(w, r, "/download/Cursor-1.3.0-x86_64.AppImage", http.StatusFound)
+		case "/versions.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`["1.1.0", "1.2.0", "1.3.0"]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		versionsURL string
+		expected    []string
+		expectErr   bool
+	}{
+		{name: "latest only without versions_url", expected: []string{"1.3.0"}},
+		{name: "versions_url", versionsURL: server.URL + "/versions.json", expected: []string{"1.1.0", "1.2.0", "1.3.0"}},
+		{name: "versions_url missing", versionsURL: server.URL + "/missing.json", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.DownloadDir = t.TempDir()
+			cfg.VersionsURL = tt.versionsURL
+
+			updater := NewUpdater(server.URL+"/download/stable/linux-x64", cfg.DownloadDir, cfg)
+
+			versions, err := updater.ListRemoteVersions()
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("Expected an error listing remote versions")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to list remote versions: %v", err)
+			}
+
+			if !reflect.DeepEqual(versions, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, versions)
+			}
+		})
+	}
+}
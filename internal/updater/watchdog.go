@@ -0,0 +1,87 @@
+package updater
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// fileSystem creates the files downloads are written to, so tests can
+// substitute a misbehaving disk
+type fileSystem interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// osFileSystem writes to the real filesystem
+type osFileSystem struct{}
+
+// Create creates or truncates the named file
+func (osFileSystem) Create(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
+
+// stallWriter tracks how long the current write has been in flight
+type stallWriter struct {
+	io.Writer
+	writeStarted atomic.Int64 // unix nanoseconds, 0 while no write is in flight
+}
+
+// Write implements io.Writer and records when the write started
+func (w *stallWriter) Write(p []byte) (int, error) {
+	w.writeStarted.Store(time.Now().UnixNano())
+	defer w.writeStarted.Store(0)
+	return w.Writer.Write(p)
+}
+
+// stalledFor returns how long the in-flight write has been blocked
+func (w *stallWriter) stalledFor() time.Duration {
+	started := w.writeStarted.Load()
+	if started == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, started))
+}
+
+// copyWithStallWatchdog copies src to dst, calling abort and returning an
+// error when a single write to dst blocks for longer than timeout. Only disk
+// writes are timed, a slow network read never triggers the watchdog. A
+// timeout of 0 disables the watchdog.
+//
+// A stalled write can't be interrupted, so after an abort the copy goroutine
+// stays alive until the blocked write returns and may read from src once
+// more. abort should make src fail, and the caller must close dst and ignore
+// any progress reported by src from then on.
+func copyWithStallWatchdog(dst io.Writer, src io.Reader, timeout time.Duration, abort func()) error {
+	if timeout <= 0 {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+
+	writer := &stallWriter{Writer: dst}
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(writer, src)
+		done <- err
+	}()
+
+	interval := timeout / 4
+	if interval <= 0 {
+		interval = timeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			if writer.stalledFor() >= timeout {
+				abort()
+				return fmt.Errorf("disk write stalled for more than %s", timeout)
+			}
+		}
+	}
+}
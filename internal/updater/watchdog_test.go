@@ -0,0 +1,110 @@
+package updater
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stalledFileSystem creates real files whose writes block until release is closed
+type stalledFileSystem struct {
+	release chan struct{}
+}
+
+func (fs stalledFileSystem) Create(name string) (io.WriteCloser, error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return stalledFile{File: file, release: fs.release}, nil
+}
+
+type stalledFile struct {
+	*os.File
+	release chan struct{}
+}
+
+func (f stalledFile) Write(p []byte) (int, error) {
+	<-f.release
+	return f.File.Write(p)
+}
+
+func TestDownloadCursorAbortsOnStalledWrite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/stable/linux-x64":
+			http.Redirect(w, r, "/download/Cursor-1.0.0-x86_64.AppImage", http.StatusFound)
+		case "/download/Cursor-1.0.0-x86_64.AppImage":
+			w.Write([]byte("downloaded content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	up, cfg := newTestUpdater(t, server.URL+"/download/stable/linux-x64")
+	cfg.WriteStallTimeout = 50 * time.Millisecond
+
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	up.fs = stalledFileSystem{release: release}
+
+	start := time.Now()
+	_, err := up.DownloadCursor()
+	if err == nil {
+		t.Fatal("Expected the download to be aborted when the disk stalls")
+	}
+
+	if !strings.Contains(err.Error(), "stalled") {
+		t.Errorf("Expected a stall error, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the watchdog to abort promptly, took %s", elapsed)
+	}
+
+	partialPath := filepath.Join(cfg.DownloadDir, "Cursor-1.0.0-x86_64.AppImage")
+	if _, err := os.Stat(partialPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial file to be removed, stat returned: %v", err)
+	}
+}
+
+func TestDownloadCursorSlowNetworkDoesNotTriggerWatchdog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/stable/linux-x64":
+			http.Redirect(w, r, "/download/Cursor-1.0.0-x86_64.AppImage", http.StatusFound)
+		case "/download/Cursor-1.0.0-x86_64.AppImage":
+			// Pause between chunks for longer than the stall timeout
+			w.Write([]byte("downloaded "))
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	up, cfg := newTestUpdater(t, server.URL+"/download/stable/linux-x64")
+	cfg.WriteStallTimeout = 50 * time.Millisecond
+
+	filename, err := up.DownloadCursor()
+	if err != nil {
+		t.Fatalf("Expected a slow network not to trigger the disk watchdog, got: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(cfg.DownloadDir, filename))
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+
+	if string(content) != "downloaded content" {
+		t.Errorf("Expected downloaded content, got %q", string(content))
+	}
+}